@@ -0,0 +1,335 @@
+// Package audit runs checks over USR02 user records
+//
+// Checks are registered by ID, like database/sql drivers, so packages
+// outside this module add their own checks with Register in init. A JSON
+// configuration enables, disables and parameterizes checks:
+//
+//	{
+//		"checks": {
+//			"weak-kind": {"clients": ["100"], "severity": "critical"},
+//			"initial-password-age": {"params": {"days": "7"}},
+//			"short-salt": {"enabled": false}
+//		}
+//	}
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dim13/codvn/usr02"
+)
+
+// Errors
+var (
+	ErrUnknownCheck = errors.New("unknown check")
+	ErrSeverity     = errors.New("unknown severity")
+	ErrParam        = errors.New("unknown parameter")
+	ErrDuplicate    = errors.New("duplicate check")
+)
+
+// Severity of finding
+type Severity int
+
+// Severities
+const (
+	Info Severity = iota
+	Low
+	Medium
+	High
+	Critical
+)
+
+var severities = []string{"info", "low", "medium", "high", "critical"}
+
+func (s Severity) String() string {
+	if s < 0 || int(s) >= len(severities) {
+		return fmt.Sprintf("severity(%d)", int(s))
+	}
+	return severities[s]
+}
+
+// MarshalText encodes severity name
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText parses severity name
+func (s *Severity) UnmarshalText(text []byte) error {
+	for i, n := range severities {
+		if strings.EqualFold(n, string(text)) {
+			*s = Severity(i)
+			return nil
+		}
+	}
+	return ErrSeverity
+}
+
+// Subject of checks
+type Subject struct {
+	System string    // system ID
+	Date   time.Time // audit date
+	usr02.Record
+	History []usr02.Entry // password history of user, if loaded
+}
+
+// Description of check
+type Description struct {
+	ID       string
+	Title    string
+	Severity Severity          // default severity
+	Params   map[string]string // parameters with defaults
+}
+
+// Check of user records
+type Check interface {
+	// Describe check
+	Describe() Description
+	// Configure parameters, unknown parameters are errors
+	Configure(params map[string]string) error
+	// Check subject, returns finding messages
+	Check(s Subject) []string
+}
+
+// Finding of check
+type Finding struct {
+	Check    string   `json:"check"`
+	Severity Severity `json:"severity"`
+	System   string   `json:"system"`
+	Client   string   `json:"client"`
+	User     string   `json:"user"`
+	Message  string   `json:"message"`
+}
+
+var (
+	mu       sync.RWMutex
+	registry = make(map[string]func() Check)
+)
+
+// Register check constructor by ID, panics on duplicates
+func Register(id string, f func() Check) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, dup := registry[id]; dup {
+		panic("audit: Register called twice for check " + id)
+	}
+	registry[id] = f
+}
+
+// Checks returns sorted IDs of registered checks
+func Checks() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	var ids []string
+	for id := range registry {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// NewCheck creates registered check
+func NewCheck(id string) (Check, error) {
+	mu.RLock()
+	f, ok := registry[id]
+	mu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownCheck
+	}
+	return f(), nil
+}
+
+// CheckConfig of a check, empty filters match everything
+type CheckConfig struct {
+	Enabled  *bool             `json:"enabled,omitempty"`
+	Severity *Severity         `json:"severity,omitempty"`
+	Params   map[string]string `json:"params,omitempty"`
+	Systems  []string          `json:"systems,omitempty"`
+	Clients  []string          `json:"clients,omitempty"`
+	Types    []string          `json:"types,omitempty"` // user types
+}
+
+// Config of audit, registered checks are enabled unless disabled
+type Config struct {
+	Checks map[string]CheckConfig `json:"checks"`
+}
+
+// LoadConfig reads JSON configuration
+func LoadConfig(path string) (Config, error) {
+	var c Config
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(data, &c)
+	return c, err
+}
+
+type configured struct {
+	Check
+	CheckConfig
+	id       string
+	severity Severity
+	params   map[string]string // effective parameters
+}
+
+func contains(list []string, s string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (c configured) applies(s Subject) bool {
+	return contains(c.Systems, s.System) && contains(c.Clients, s.Client) && contains(c.Types, s.Type)
+}
+
+// Auditor runs configured checks
+type Auditor struct {
+	Date    time.Time // audit date, today if zero
+	checks  []configured
+	history map[string][]usr02.Entry
+}
+
+// New auditor of all registered checks configured by c, extra checks are
+// added as if registered and must not reuse IDs
+func New(c Config, extra ...Check) (*Auditor, error) {
+	all := make(map[string]Check)
+	for _, id := range Checks() {
+		chk, err := NewCheck(id)
+		if err != nil {
+			return nil, err
+		}
+		all[id] = chk
+	}
+	for _, chk := range extra {
+		id := chk.Describe().ID
+		if _, dup := all[id]; dup {
+			return nil, fmt.Errorf("%s: %v", id, ErrDuplicate)
+		}
+		all[id] = chk
+	}
+	for id := range c.Checks {
+		if _, ok := all[id]; !ok {
+			return nil, fmt.Errorf("%s: %v", id, ErrUnknownCheck)
+		}
+	}
+	var ids []string
+	for id := range all {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	a := &Auditor{}
+	for _, id := range ids {
+		chk, cc := all[id], c.Checks[id]
+		if cc.Enabled != nil && !*cc.Enabled {
+			continue
+		}
+		if err := chk.Configure(cc.Params); err != nil {
+			return nil, fmt.Errorf("%s: %v", id, err)
+		}
+		sev := chk.Describe().Severity
+		if cc.Severity != nil {
+			sev = *cc.Severity
+		}
+		params, err := Params(chk.Describe(), cc.Params)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", id, err)
+		}
+		a.checks = append(a.checks, configured{Check: chk, CheckConfig: cc, id: id, severity: sev, params: params})
+	}
+	return a, nil
+}
+
+// Enabled returns IDs of enabled checks
+func (a *Auditor) Enabled() []string {
+	var ids []string
+	for _, c := range a.checks {
+		ids = append(ids, c.id)
+	}
+	return ids
+}
+
+func historyKey(client, user string) string {
+	return client + "\x00" + user
+}
+
+// AddHistory adds USH02 or USRPWDHISTORY entries to subjects of their users
+func (a *Auditor) AddHistory(entries ...usr02.Entry) {
+	if a.history == nil {
+		a.history = make(map[string][]usr02.Entry)
+	}
+	for _, e := range entries {
+		k := historyKey(e.Client, e.User)
+		a.history[k] = append(a.history[k], e)
+	}
+}
+
+// Effective descriptions of enabled checks, with configured severity and
+// parameters
+func (a *Auditor) Effective() []Description {
+	var ds []Description
+	for _, c := range a.checks {
+		d := c.Describe()
+		d.Severity, d.Params = c.severity, c.params
+		ds = append(ds, d)
+	}
+	return ds
+}
+
+// Audit record of system
+func (a *Auditor) Audit(system string, r usr02.Record) []Finding {
+	s := Subject{System: system, Date: a.Date, Record: r, History: a.history[historyKey(r.Client, r.User)]}
+	if s.Date.IsZero() {
+		s.Date = time.Now()
+	}
+	var findings []Finding
+	for _, c := range a.checks {
+		if !c.applies(s) {
+			continue
+		}
+		for _, msg := range c.Check.Check(s) {
+			findings = append(findings, Finding{
+				Check:    c.id,
+				Severity: c.severity,
+				System:   system,
+				Client:   r.Client,
+				User:     r.User,
+				Message:  msg,
+			})
+		}
+	}
+	return findings
+}
+
+// Run audit over all records of dump, fn is called with findings of each
+// record if not nil
+func (a *Auditor) Run(system string, r *usr02.Reader, fn func(usr02.Record, []Finding)) ([]Finding, error) {
+	var findings []Finding
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return findings, nil
+		}
+		if err != nil {
+			return findings, err
+		}
+		f := a.Audit(system, rec)
+		if fn != nil {
+			fn(rec, f)
+		}
+		findings = append(findings, f...)
+	}
+}
@@ -0,0 +1,188 @@
+package audit
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dim13/codvn"
+	"github.com/dim13/codvn/usr02"
+)
+
+func mustHash(t *testing.T, kind codvn.Kind, pass string, salt, iter int) codvn.CodvN {
+	h, err := codvn.New(kind, []byte(pass), make([]byte, salt), iter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+// noRFC is a custom check written outside of built-ins
+type noRFC struct{}
+
+func (noRFC) Describe() Description {
+	return Description{ID: "no-rfc-prefix", Title: "technical users start with RFC_", Severity: Low}
+}
+
+func (c noRFC) Configure(params map[string]string) error {
+	_, err := Params(c.Describe(), params)
+	return err
+}
+
+func (noRFC) Check(s Subject) []string {
+	if s.Type == usr02.Communication && !strings.HasPrefix(s.User, "RFC_") {
+		return []string{"no RFC_ prefix"}
+	}
+	return nil
+}
+
+func ids(findings []Finding) string {
+	var s []string
+	for _, f := range findings {
+		s = append(s, f.Check+":"+f.Severity.String())
+	}
+	return strings.Join(s, " ")
+}
+
+func TestAuditor(t *testing.T) {
+	sha1 := mustHash(t, codvn.SHA1, "secret", 12, 1024)
+	testCases := []struct {
+		title  string
+		config string
+		system string
+		rec    usr02.Record
+		want   string
+	}{
+		{
+			title: "default",
+			rec:   usr02.Record{Client: "100", User: "U1", PwdSaltedHash: sha1},
+			want:  "low-iterations:medium short-salt:low weak-kind:high",
+		},
+		{
+			title:  "disabled",
+			config: `{"checks": {"short-salt": {"enabled": false}, "low-iterations": {"params": {"min": "1000"}}}}`,
+			rec:    usr02.Record{Client: "100", User: "U1", PwdSaltedHash: sha1},
+			want:   "weak-kind:high",
+		},
+		{
+			title:  "production client",
+			config: `{"checks": {"weak-kind": {"clients": ["100"], "severity": "critical"}, "short-salt": {"enabled": false}, "low-iterations": {"enabled": false}}}`,
+			rec:    usr02.Record{Client: "100", User: "U1", PwdSaltedHash: sha1},
+			want:   "weak-kind:critical",
+		},
+		{
+			title:  "other client",
+			config: `{"checks": {"weak-kind": {"clients": ["100"]}, "short-salt": {"enabled": false}, "low-iterations": {"enabled": false}}}`,
+			rec:    usr02.Record{Client: "200", User: "U1", PwdSaltedHash: sha1},
+		},
+		{
+			title:  "system",
+			config: `{"checks": {"legacy-hash": {"systems": ["PRD"]}}}`,
+			system: "QAS",
+			rec:    usr02.Record{Client: "100", User: "U1", Bcode: make(usr02.Bcode, 8)},
+		},
+		{
+			title: "custom",
+			rec:   usr02.Record{Client: "100", User: "IF_USER", Type: usr02.Communication},
+			want:  "no-rfc-prefix:low",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			var c Config
+			if tc.config != "" {
+				if err := json.Unmarshal([]byte(tc.config), &c); err != nil {
+					t.Fatal(err)
+				}
+			}
+			a, err := New(c, noRFC{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ids(a.Audit(tc.system, tc.rec)); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	for _, config := range []string{
+		`{"checks": {"no-such-check": {}}}`,
+		`{"checks": {"short-salt": {"params": {"max": "1"}}}}`,
+		`{"checks": {"short-salt": {"params": {"min": "x"}}}}`,
+	} {
+		var c Config
+		if err := json.Unmarshal([]byte(config), &c); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := New(c); err == nil {
+			t.Errorf("%s: no error", config)
+		}
+	}
+	var c Config
+	if err := json.Unmarshal([]byte(`{"checks": {"short-salt": {"severity": "urgent"}}}`), &c); err == nil {
+		t.Error("unknown severity accepted")
+	}
+	if _, err := New(Config{}, noRFC{}, noRFC{}); err == nil {
+		t.Error("duplicate check accepted")
+	}
+	if _, err := New(Config{}, &shortSalt{}); err == nil {
+		t.Error("built-in check replaced")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("duplicate registration accepted")
+		}
+	}()
+	Register("weak-kind", func() Check { return &weakKind{} })
+}
+
+func TestRun(t *testing.T) {
+	dump := "MANDT\tBNAME\tPWDSALTEDHASH\n" +
+		"000\tSAP*\t" + mustHash(t, codvn.SHA512, "06071992", 16, 15000).String() + "\n" +
+		"000\tDDIC\t" + mustHash(t, codvn.SHA512, "secret", 16, 15000).String() + "\n"
+	a, err := New(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Date = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var users []string
+	findings, err := a.Run("PRD", usr02.NewReader(strings.NewReader(dump), '\t'), func(r usr02.Record, _ []Finding) {
+		users = append(users, r.User)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 {
+		t.Errorf("got %v, want callback per record", users)
+	}
+	if len(findings) != 1 || findings[0].User != "SAP*" || findings[0].Check != "default-password" {
+		t.Errorf("got %+v", findings)
+	}
+}
+
+func TestEffective(t *testing.T) {
+	var c Config
+	if err := json.Unmarshal([]byte(`{"checks": {"short-salt": {"severity": "critical", "params": {"min": "12"}}}}`), &c); err != nil {
+		t.Fatal(err)
+	}
+	a, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range a.Effective() {
+		if d.ID != "short-salt" {
+			continue
+		}
+		if d.Severity != Critical || d.Params["min"] != "12" {
+			t.Errorf("got %+v, want configured severity and parameters", d)
+		}
+		return
+	}
+	t.Error("short-salt not enabled")
+}
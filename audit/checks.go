@@ -0,0 +1,226 @@
+package audit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dim13/codvn"
+	"github.com/dim13/codvn/usr02"
+)
+
+func init() {
+	Register("weak-kind", func() Check { return &weakKind{} })
+	Register("low-iterations", func() Check { return &lowIterations{} })
+	Register("short-salt", func() Check { return &shortSalt{} })
+	Register("legacy-hash", func() Check { return &legacyHash{} })
+	Register("default-password", func() Check { return &defaultPassword{} })
+	Register("initial-password-age", func() Check { return &initialAge{} })
+}
+
+// Params merges known parameters over defaults of description
+func Params(d Description, params map[string]string) (map[string]string, error) {
+	merged := make(map[string]string)
+	for k, v := range d.Params {
+		merged[k] = v
+	}
+	for k, v := range params {
+		if _, ok := d.Params[k]; !ok {
+			return nil, fmt.Errorf("%s: %v", k, ErrParam)
+		}
+		merged[k] = v
+	}
+	return merged, nil
+}
+
+// intParam configures single integer parameter
+func intParam(d Description, params map[string]string, name string, v *int) error {
+	p, err := Params(d, params)
+	if err != nil {
+		return err
+	}
+	*v, err = strconv.Atoi(p[name])
+	return err
+}
+
+// list splits comma separated parameter
+func list(s string) []string {
+	var l []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			l = append(l, v)
+		}
+	}
+	return l
+}
+
+type weakKind struct {
+	kinds []codvn.Kind
+}
+
+func (c *weakKind) Describe() Description {
+	return Description{
+		ID:       "weak-kind",
+		Title:    "PWDSALTEDHASH uses weak algorithm",
+		Severity: High,
+		Params:   map[string]string{"kinds": string(codvn.SHA1)},
+	}
+}
+
+func (c *weakKind) Configure(params map[string]string) error {
+	p, err := Params(c.Describe(), params)
+	if err != nil {
+		return err
+	}
+	c.kinds = nil
+	for _, k := range list(p["kinds"]) {
+		c.kinds = append(c.kinds, codvn.Kind(k))
+	}
+	return nil
+}
+
+func (c *weakKind) Check(s Subject) []string {
+	for _, k := range c.kinds {
+		if s.HasHash() && s.PwdSaltedHash.Kind == k {
+			return []string{fmt.Sprintf("algorithm %s", k)}
+		}
+	}
+	return nil
+}
+
+type lowIterations struct {
+	min int
+}
+
+func (c *lowIterations) Describe() Description {
+	return Description{
+		ID:       "low-iterations",
+		Title:    "PWDSALTEDHASH has too few iterations",
+		Severity: Medium,
+		Params:   map[string]string{"min": "10000"},
+	}
+}
+
+func (c *lowIterations) Configure(params map[string]string) error {
+	return intParam(c.Describe(), params, "min", &c.min)
+}
+
+func (c *lowIterations) Check(s Subject) []string {
+	if s.HasHash() && s.PwdSaltedHash.Iter < c.min {
+		return []string{fmt.Sprintf("%d iterations, want at least %d", s.PwdSaltedHash.Iter, c.min)}
+	}
+	return nil
+}
+
+type shortSalt struct {
+	min int
+}
+
+func (c *shortSalt) Describe() Description {
+	return Description{
+		ID:       "short-salt",
+		Title:    "PWDSALTEDHASH has short salt",
+		Severity: Low,
+		Params:   map[string]string{"min": "16"},
+	}
+}
+
+func (c *shortSalt) Configure(params map[string]string) error {
+	return intParam(c.Describe(), params, "min", &c.min)
+}
+
+func (c *shortSalt) Check(s Subject) []string {
+	if n := len(s.PwdSaltedHash.Salt); s.HasHash() && n < c.min {
+		return []string{fmt.Sprintf("%d bytes salt, want at least %d", n, c.min)}
+	}
+	return nil
+}
+
+type legacyHash struct{}
+
+func (c *legacyHash) Describe() Description {
+	return Description{
+		ID:       "legacy-hash",
+		Title:    "legacy BCODE or PASSCODE present",
+		Severity: High,
+	}
+}
+
+func (c *legacyHash) Configure(params map[string]string) error {
+	_, err := Params(c.Describe(), params)
+	return err
+}
+
+func (c *legacyHash) Check(s Subject) []string {
+	var msgs []string
+	if !s.Bcode.IsZero() {
+		msgs = append(msgs, "BCODE present")
+	}
+	if !s.Passcode.IsZero() {
+		msgs = append(msgs, "PASSCODE present")
+	}
+	return msgs
+}
+
+// DefaultPasswords of standard users
+var DefaultPasswords = map[string][]string{
+	"SAP*":       {"06071992", "PASS"},
+	"DDIC":       {"19920706"},
+	"EARLYWATCH": {"support"},
+	"SAPCPIC":    {"admin"},
+	"TMSADM":     {"PASSWORD", "$1Pawd2&"},
+}
+
+type defaultPassword struct{}
+
+func (c *defaultPassword) Describe() Description {
+	return Description{
+		ID:       "default-password",
+		Title:    "standard user has default password",
+		Severity: Critical,
+	}
+}
+
+func (c *defaultPassword) Configure(params map[string]string) error {
+	_, err := Params(c.Describe(), params)
+	return err
+}
+
+func (c *defaultPassword) Check(s Subject) []string {
+	for _, pass := range DefaultPasswords[s.User] {
+		if s.HasHash() && s.PwdSaltedHash.Verify([]byte(pass)) == nil {
+			return []string{"default password"}
+		}
+		if s.Passcode.Verify(s.User, []byte(pass)) {
+			return []string{"default password in PASSCODE"}
+		}
+	}
+	return nil
+}
+
+type initialAge struct {
+	days int
+}
+
+func (c *initialAge) Describe() Description {
+	return Description{
+		ID:       "initial-password-age",
+		Title:    "initial password unchanged for too long",
+		Severity: Medium,
+		Params:   map[string]string{"days": "7"},
+	}
+}
+
+func (c *initialAge) Configure(params map[string]string) error {
+	return intParam(c.Describe(), params, "days", &c.days)
+}
+
+func (c *initialAge) Check(s Subject) []string {
+	if s.PwdState != usr02.PwdInitial || s.PwdChanged.IsZero() {
+		return nil
+	}
+	if days := int(s.Date.Sub(s.PwdChanged).Hours() / 24); days > c.days {
+		return []string{fmt.Sprintf("initial password set %d days ago", days)}
+	}
+	return nil
+}
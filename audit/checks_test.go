@@ -0,0 +1,50 @@
+package audit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dim13/codvn"
+	"github.com/dim13/codvn/usr02"
+)
+
+func TestChecks(t *testing.T) {
+	date := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		check  string
+		params map[string]string
+		rec    usr02.Record
+		want   int
+	}{
+		{check: "weak-kind", rec: usr02.Record{PwdSaltedHash: mustHash(t, codvn.SHA1, "x", 12, 1)}, want: 1},
+		{check: "weak-kind", rec: usr02.Record{PwdSaltedHash: mustHash(t, codvn.SHA256, "x", 16, 1)}},
+		{check: "weak-kind", params: map[string]string{"kinds": "sha,SHA256"}, rec: usr02.Record{PwdSaltedHash: mustHash(t, codvn.SHA256, "x", 16, 1)}, want: 1},
+		{check: "weak-kind"},
+		{check: "low-iterations", rec: usr02.Record{PwdSaltedHash: mustHash(t, codvn.SHA512, "x", 16, 5000)}, want: 1},
+		{check: "low-iterations", rec: usr02.Record{PwdSaltedHash: mustHash(t, codvn.SHA512, "x", 16, 10000)}},
+		{check: "short-salt", rec: usr02.Record{PwdSaltedHash: mustHash(t, codvn.SHA512, "x", 8, 1)}, want: 1},
+		{check: "legacy-hash", rec: usr02.Record{Bcode: make(usr02.Bcode, 8), Passcode: make(usr02.Passcode, 20)}, want: 2},
+		{check: "legacy-hash"},
+		{check: "default-password", rec: usr02.Record{User: "DDIC", PwdSaltedHash: mustHash(t, codvn.SHA512, "19920706", 16, 1)}, want: 1},
+		{check: "default-password", rec: usr02.Record{User: "DDIC", PwdSaltedHash: mustHash(t, codvn.SHA512, "06071992", 16, 1)}},
+		{check: "default-password", rec: usr02.Record{User: "DDIC", Passcode: usr02.NewPasscode("DDIC", []byte("19920706"))}, want: 1},
+		{check: "default-password", rec: usr02.Record{User: "DDIC", Passcode: usr02.NewPasscode("DDIC", []byte("06071992"))}},
+		{check: "initial-password-age", rec: usr02.Record{PwdState: usr02.PwdInitial, PwdChanged: date.AddDate(0, 0, -8)}, want: 1},
+		{check: "initial-password-age", rec: usr02.Record{PwdState: usr02.PwdInitial, PwdChanged: date.AddDate(0, 0, -7)}},
+		{check: "initial-password-age", rec: usr02.Record{PwdState: usr02.PwdProductive, PwdChanged: date.AddDate(0, 0, -30)}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.check, func(t *testing.T) {
+			c, err := NewCheck(tc.check)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := c.Configure(tc.params); err != nil {
+				t.Fatal(err)
+			}
+			if got := c.Check(Subject{Date: date, Record: tc.rec}); len(got) != tc.want {
+				t.Errorf("got %q, want %d findings", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path"
+	"time"
+)
+
+// Errors
+var (
+	ErrException = errors.New("exception needs check, justification, approver and expiry")
+)
+
+// Date without time of day, formatted as 2006-01-02
+type Date struct {
+	time.Time
+}
+
+const dateLayout = "2006-01-02"
+
+func (d Date) String() string {
+	return d.Format(dateLayout)
+}
+
+// MarshalText encodes date
+func (d Date) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText parses date
+func (d *Date) UnmarshalText(text []byte) (err error) {
+	d.Time, err = time.Parse(dateLayout, string(text))
+	return err
+}
+
+// MarshalJSON overrides time.Time encoding
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON overrides time.Time decoding
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return d.UnmarshalText([]byte(s))
+}
+
+// Exception accepts findings as risk until it expires. System, client and
+// user are shell patterns, empty patterns match everything, check "*"
+// matches all checks.
+type Exception struct {
+	Check         string `json:"check"`
+	System        string `json:"system,omitempty"`
+	Client        string `json:"client,omitempty"`
+	User          string `json:"user,omitempty"`
+	Justification string `json:"justification"`
+	Approver      string `json:"approver"`
+	Expires       Date   `json:"expires"` // last day of validity
+}
+
+// Expired reports whether exception has expired at date
+func (e Exception) Expired(date time.Time) bool {
+	return !date.Before(e.Expires.AddDate(0, 0, 1))
+}
+
+func match(pattern, s string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(pattern, s)
+	return ok
+}
+
+// Matches reports whether exception covers finding
+func (e Exception) Matches(f Finding) bool {
+	return (e.Check == "*" || e.Check == f.Check) &&
+		match(e.System, f.System) &&
+		match(e.Client, f.Client) &&
+		match(e.User, f.User)
+}
+
+// LoadExceptions reads JSON exceptions file:
+//
+//	{"exceptions": [{"check": "legacy-hash", "system": "PRD", "client": "100",
+//		"user": "IF_*", "justification": "...", "approver": "...",
+//		"expires": "2027-03-31"}]}
+func LoadExceptions(file string) ([]Exception, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var v struct {
+		Exceptions []Exception `json:"exceptions"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	for _, e := range v.Exceptions {
+		if e.Check == "" || e.Justification == "" || e.Approver == "" || e.Expires.IsZero() {
+			return nil, ErrException
+		}
+		for _, p := range []string{e.System, e.Client, e.User} {
+			if _, err := path.Match(p, ""); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return v.Exceptions, nil
+}
+
+// ExceptionStatus after suppression
+type ExceptionStatus struct {
+	Exception
+	Suppressed int  // findings suppressed
+	Expired    bool // expired, findings are reported again
+	Matched    int  // findings matched, even if expired
+}
+
+// Unused reports whether active exception matched no finding
+func (s ExceptionStatus) Unused() bool {
+	return !s.Expired && s.Matched == 0
+}
+
+// Suppress findings covered by active exceptions at date, returns remaining
+// findings and status of every exception
+func Suppress(findings []Finding, exceptions []Exception, date time.Time) ([]Finding, []ExceptionStatus) {
+	status := make([]ExceptionStatus, len(exceptions))
+	for i, e := range exceptions {
+		status[i] = ExceptionStatus{Exception: e, Expired: e.Expired(date)}
+	}
+	var kept []Finding
+	for _, f := range findings {
+		suppressed := false
+		for i := range status {
+			if !status[i].Matches(f) {
+				continue
+			}
+			status[i].Matched++
+			if !status[i].Expired && !suppressed {
+				status[i].Suppressed++
+				suppressed = true
+			}
+		}
+		if !suppressed {
+			kept = append(kept, f)
+		}
+	}
+	return kept, status
+}
@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSuppress(t *testing.T) {
+	date := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	exc := []Exception{
+		{Check: "legacy-hash", System: "PRD", Client: "100", User: "IF_*", Expires: Date{time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)}},
+		{Check: "*", User: "SAP[*]", Expires: Date{time.Date(2026, 3, 30, 0, 0, 0, 0, time.UTC)}},
+		{Check: "weak-kind", Expires: Date{time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)}},
+	}
+	findings := []Finding{
+		{Check: "legacy-hash", System: "PRD", Client: "100", User: "IF_SALES"},
+		{Check: "legacy-hash", System: "PRD", Client: "200", User: "IF_SALES"},
+		{Check: "legacy-hash", System: "QAS", Client: "100", User: "IF_SALES"},
+		{Check: "short-salt", System: "PRD", Client: "100", User: "IF_SALES"},
+		{Check: "default-password", System: "PRD", Client: "000", User: "SAP*"},
+	}
+	kept, status := Suppress(findings, exc, date)
+	if len(kept) != 4 {
+		t.Errorf("got %d findings, want 4", len(kept))
+	}
+	want := []struct {
+		suppressed, matched int
+		expired, unused     bool
+	}{
+		{1, 1, false, false},
+		{0, 1, true, false},
+		{0, 0, false, true},
+	}
+	for i, w := range want {
+		s := status[i]
+		if s.Suppressed != w.suppressed || s.Matched != w.matched || s.Expired != w.expired || s.Unused() != w.unused {
+			t.Errorf("%d: got %+v, want %+v", i, s, w)
+		}
+	}
+}
+
+func TestLoadExceptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "exceptions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "exceptions.json")
+	testCases := []struct {
+		title string
+		data  string
+		err   bool
+	}{
+		{title: "valid", data: `{"exceptions": [{"check": "legacy-hash", "user": "IF_*", "justification": "decommissioned 2027", "approver": "CISO", "expires": "2027-03-31"}]}`},
+		{title: "approver", data: `{"exceptions": [{"check": "legacy-hash", "justification": "x", "expires": "2027-03-31"}]}`, err: true},
+		{title: "date", data: `{"exceptions": [{"check": "legacy-hash", "justification": "x", "approver": "y", "expires": "31.03.2027"}]}`, err: true},
+		{title: "pattern", data: `{"exceptions": [{"check": "legacy-hash", "user": "[", "justification": "x", "approver": "y", "expires": "2027-03-31"}]}`, err: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			if err := ioutil.WriteFile(file, []byte(tc.data), 0600); err != nil {
+				t.Fatal(err)
+			}
+			exc, err := LoadExceptions(file)
+			if (err != nil) != tc.err {
+				t.Fatalf("got %v", err)
+			}
+			if err == nil && (len(exc) != 1 || exc[0].Expires.String() != "2027-03-31") {
+				t.Errorf("got %+v", exc)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/dim13/codvn"
+	"github.com/dim13/codvn/usr02"
+)
+
+// Run summarizes audit of a system
+type Run struct {
+	Time       time.Time          `json:"time"`
+	System     string             `json:"system"`
+	Users      int                `json:"users"`
+	Kinds      map[codvn.Kind]int `json:"kinds"` // PWDSALTEDHASH algorithms
+	MedianIter int                `json:"median_iter"`
+	Bcode      int                `json:"bcode"`    // users with BCODE
+	Passcode   int                `json:"passcode"` // users with PASSCODE
+	Findings   map[string]int     `json:"findings"` // by check, before exceptions
+	Checks     []string           `json:"checks"`   // enabled checks
+}
+
+// Share of PWDSALTEDHASH of kind among users having one, in percent
+func (r Run) Share(kind codvn.Kind) float64 {
+	var hashed int
+	for _, n := range r.Kinds {
+		hashed += n
+	}
+	if hashed == 0 {
+		return 0
+	}
+	return 100 * float64(r.Kinds[kind]) / float64(hashed)
+}
+
+// Ran reports whether check was enabled, runs recorded without checks are
+// assumed to have run all
+func (r Run) Ran(check string) bool {
+	if r.Checks == nil {
+		return true
+	}
+	for _, c := range r.Checks {
+		if c == check {
+			return true
+		}
+	}
+	return false
+}
+
+// Tally accumulates run summary
+type Tally struct {
+	run   Run
+	iters []int
+}
+
+// NewTally of system audited at time with enabled checks
+func NewTally(system string, t time.Time, checks []string) *Tally {
+	return &Tally{run: Run{
+		Time:     t,
+		System:   system,
+		Kinds:    make(map[codvn.Kind]int),
+		Findings: make(map[string]int),
+		Checks:   append([]string{}, checks...),
+	}}
+}
+
+// Add record and its findings
+func (t *Tally) Add(r usr02.Record, findings []Finding) {
+	t.run.Users++
+	if r.HasHash() {
+		t.run.Kinds[r.PwdSaltedHash.Kind]++
+		t.iters = append(t.iters, r.PwdSaltedHash.Iter)
+	}
+	if !r.Bcode.IsZero() {
+		t.run.Bcode++
+	}
+	if !r.Passcode.IsZero() {
+		t.run.Passcode++
+	}
+	for _, f := range findings {
+		t.run.Findings[f.Check]++
+	}
+}
+
+// Run summary
+func (t *Tally) Run() Run {
+	r := t.run
+	if n := len(t.iters); n > 0 {
+		sort.Ints(t.iters)
+		r.MedianIter = t.iters[n/2]
+		if n%2 == 0 {
+			r.MedianIter = (t.iters[n/2-1] + t.iters[n/2]) / 2
+		}
+	}
+	return r
+}
+
+// AppendRun to history file of JSON lines
+func AppendRun(path string, r Run) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadRuns from history file, ordered by time
+func LoadRuns(path string) ([]Run, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var runs []Run
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for dec.More() {
+		var r Run
+		if err := dec.Decode(&r); err != nil {
+			return nil, err
+		}
+		runs = append(runs, r)
+	}
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].Time.Before(runs[j].Time) })
+	return runs, nil
+}
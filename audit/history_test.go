@@ -0,0 +1,99 @@
+package audit
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dim13/codvn"
+	"github.com/dim13/codvn/usr02"
+)
+
+func TestTally(t *testing.T) {
+	tl := NewTally("PRD", time.Now(), []string{"weak-kind"})
+	for i, iter := range []int{1024, 15000, 15000, 10000} {
+		kind := codvn.SHA512
+		if iter == 1024 {
+			kind = codvn.SHA1
+		}
+		r := usr02.Record{PwdSaltedHash: mustHash(t, kind, "x", 16, 1)}
+		r.PwdSaltedHash.Iter = iter
+		if i == 0 {
+			r.Bcode = make(usr02.Bcode, usr02.BcodeSize)
+		}
+		tl.Add(r, []Finding{{Check: "weak-kind"}})
+	}
+	tl.Add(usr02.Record{Passcode: make(usr02.Passcode, usr02.PasscodeSize)}, nil)
+	r := tl.Run()
+	if r.Users != 5 || r.Kinds[codvn.SHA512] != 3 || r.MedianIter != 12500 || r.Bcode != 1 || r.Passcode != 1 || r.Findings["weak-kind"] != 4 {
+		t.Errorf("got %+v", r)
+	}
+	if s := r.Share(codvn.SHA1); s != 25 {
+		t.Errorf("got share %v, want 25 of hashed users", s)
+	}
+	if !r.Ran("weak-kind") || r.Ran("default-password") {
+		t.Errorf("got checks %v", r.Checks)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "history.jsonl")
+	q := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, sys := range []string{"PRD", "QAS", "PRD", "PRD"} {
+		r := Run{
+			Time:       q.AddDate(0, 3*(3-i), 0),
+			System:     sys,
+			Users:      100,
+			Kinds:      map[codvn.Kind]int{codvn.SHA1: 40 - 10*i, codvn.SHA512: 60 + 10*i},
+			MedianIter: 15000,
+			Findings:   map[string]int{"default-password": 3 - i},
+		}
+		if i == 2 {
+			r.Checks = []string{"weak-kind"}
+		}
+		if err := AppendRun(path, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runs, err := LoadRuns(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 4 || !runs[0].Time.Before(runs[3].Time) || runs[0].Kinds[codvn.SHA1] != 10 {
+		t.Fatalf("got %+v", runs)
+	}
+
+	var buf bytes.Buffer
+	if err := Report(&buf, runs); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"<h2>PRD</h2>", "<h2>QAS</h2>", "<svg", "<polyline", "Median iterations", ">sha<", ">SHA512<"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report lacks %q", want)
+		}
+	}
+	for _, external := range []string{"<script", "<link", "src=", "@import"} {
+		if strings.Contains(out, external) {
+			t.Errorf("report refers external asset %q", external)
+		}
+	}
+	if n := strings.Count(out, "<svg"); n != 8 {
+		t.Errorf("got %d charts, want 8", n)
+	}
+	// PRD default-password chart skips run without check, 2 points left
+	prd := out[strings.Index(out, "<h2>PRD</h2>"):strings.Index(out, "<h2>QAS</h2>")]
+	chart := prd[strings.Index(prd, "Default password hits"):]
+	if n := strings.Count(chart, "<circle"); n != 2 {
+		t.Errorf("got %d points, want 2", n)
+	}
+}
@@ -0,0 +1,166 @@
+package audit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dim13/codvn"
+	"github.com/dim13/codvn/usr02"
+)
+
+func init() {
+	Register("weak-history", func() Check { return &weakHistory{} })
+	Register("legacy-history", func() Check { return &legacyHistory{} })
+	Register("history-depth", func() Check { return &historyDepth{} })
+}
+
+func changed(e usr02.Entry) string {
+	if e.Changed.IsZero() {
+		return "unknown date"
+	}
+	return e.Changed.Format("2006-01-02")
+}
+
+// hashed entries of history, a change exported from both USH02 and
+// USRPWDHISTORY counts once. Hashes are salted and legacy ones can't be
+// reused within history, so equal password columns are the same change.
+func hashed(history []usr02.Entry) []usr02.Entry {
+	var entries []usr02.Entry
+	seen := make(map[string]bool)
+	for _, e := range history {
+		if !e.HasHash() && !e.HasLegacy() {
+			continue
+		}
+		key := e.Bcode.String() + "\x00" + e.Passcode.String()
+		if e.HasHash() {
+			key += "\x00" + e.PwdSaltedHash.String()
+		}
+		if !seen[key] {
+			seen[key] = true
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+type weakHistory struct {
+	kinds   []codvn.Kind
+	minIter int
+	minSalt int
+}
+
+func (c *weakHistory) Describe() Description {
+	return Description{
+		ID:       "weak-history",
+		Title:    "password history keeps weak PWDSALTEDHASH",
+		Severity: Medium,
+		Params: map[string]string{
+			"kinds":          string(codvn.SHA1),
+			"min-iterations": "10000",
+			"min-salt":       "16",
+		},
+	}
+}
+
+func (c *weakHistory) Configure(params map[string]string) error {
+	p, err := Params(c.Describe(), params)
+	if err != nil {
+		return err
+	}
+	c.kinds = nil
+	for _, k := range list(p["kinds"]) {
+		c.kinds = append(c.kinds, codvn.Kind(k))
+	}
+	if c.minIter, err = strconv.Atoi(p["min-iterations"]); err != nil {
+		return err
+	}
+	c.minSalt, err = strconv.Atoi(p["min-salt"])
+	return err
+}
+
+func (c *weakHistory) Check(s Subject) []string {
+	var msgs []string
+	for _, e := range hashed(s.History) {
+		if !e.HasHash() {
+			continue
+		}
+		h := e.PwdSaltedHash
+		var weak []string
+		for _, k := range c.kinds {
+			if h.Kind == k {
+				weak = append(weak, fmt.Sprintf("algorithm %s", k))
+			}
+		}
+		if h.Iter < c.minIter {
+			weak = append(weak, fmt.Sprintf("%d iterations", h.Iter))
+		}
+		if len(h.Salt) < c.minSalt {
+			weak = append(weak, fmt.Sprintf("%d bytes salt", len(h.Salt)))
+		}
+		if len(weak) > 0 {
+			msgs = append(msgs, fmt.Sprintf("entry of %s: %s", changed(e), strings.Join(weak, ", ")))
+		}
+	}
+	return msgs
+}
+
+type legacyHistory struct{}
+
+func (c *legacyHistory) Describe() Description {
+	return Description{
+		ID:       "legacy-history",
+		Title:    "password history keeps legacy BCODE or PASSCODE",
+		Severity: High,
+	}
+}
+
+func (c *legacyHistory) Configure(params map[string]string) error {
+	_, err := Params(c.Describe(), params)
+	return err
+}
+
+func (c *legacyHistory) Check(s Subject) []string {
+	entries := hashed(s.History)
+	var n int
+	for _, e := range entries {
+		if e.HasLegacy() {
+			n++
+		}
+	}
+	if n > 0 {
+		return []string{fmt.Sprintf("%d of %d entries with BCODE or PASSCODE", n, len(entries))}
+	}
+	return nil
+}
+
+type historyDepth struct {
+	size int
+}
+
+func (c *historyDepth) Describe() Description {
+	return Description{
+		ID:       "history-depth",
+		Title:    "USRPWDHISTORY deeper than login/password_history_size",
+		Severity: Low,
+		Params:   map[string]string{"size": "5"},
+	}
+}
+
+func (c *historyDepth) Configure(params map[string]string) error {
+	return intParam(c.Describe(), params, "size", &c.size)
+}
+
+// Check counts USRPWDHISTORY entries only, USH02 keeps every change
+func (c *historyDepth) Check(s Subject) []string {
+	var n int
+	for _, e := range s.History {
+		if e.Table == usr02.TablePwdHistory {
+			n++
+		}
+	}
+	if n > c.size {
+		return []string{fmt.Sprintf("%d entries, login/password_history_size is %d", n, c.size)}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package audit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dim13/codvn"
+	"github.com/dim13/codvn/usr02"
+)
+
+func TestHistoryChecks(t *testing.T) {
+	old := time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)
+	weak := usr02.Entry{Record: usr02.Record{PwdSaltedHash: mustHash(t, codvn.SHA1, "x", 12, 1024)}, Changed: old}
+	weak2 := usr02.Entry{Record: usr02.Record{PwdSaltedHash: mustHash(t, codvn.SHA1, "y", 12, 1024)}}
+	strong := usr02.Entry{Record: usr02.Record{PwdSaltedHash: mustHash(t, codvn.SHA512, "x", 16, 15000)}}
+	legacy := usr02.Entry{Record: usr02.Record{Codvn: usr02.CodvnB, Bcode: make(usr02.Bcode, usr02.BcodeSize)}}
+	// same changes exported from both tables
+	ush02 := func(e usr02.Entry) usr02.Entry { e.Table = usr02.TableUSH02; return e }
+	pwd := func(e usr02.Entry) usr02.Entry { e.Table = usr02.TablePwdHistory; return e }
+	var depth []usr02.Entry
+	for i := 0; i < 6; i++ {
+		e := usr02.Entry{Record: usr02.Record{PwdSaltedHash: mustHash(t, codvn.SHA512, fmt.Sprint("x", i), 16, 15000)}}
+		depth = append(depth, pwd(e))
+	}
+	testCases := []struct {
+		check   string
+		params  map[string]string
+		history []usr02.Entry
+		want    int
+		msg     string
+	}{
+		{check: "weak-history", history: []usr02.Entry{weak, strong, weak2}, want: 2},
+		{check: "weak-history", history: []usr02.Entry{ush02(weak), pwd(weak), strong}, want: 1},
+		{check: "weak-history", history: []usr02.Entry{strong, legacy}},
+		{check: "weak-history", params: map[string]string{"min-iterations": "20000"}, history: []usr02.Entry{strong}, want: 1},
+		{check: "weak-history"},
+		{check: "legacy-history", history: []usr02.Entry{legacy, strong}, want: 1},
+		{check: "legacy-history", history: []usr02.Entry{ush02(legacy), pwd(legacy), strong}, want: 1, msg: "1 of 2 entries with BCODE or PASSCODE"},
+		{check: "legacy-history", history: []usr02.Entry{strong}},
+		{check: "history-depth", history: depth, want: 1},
+		{check: "history-depth", history: depth[:5]},
+		{check: "history-depth", history: append(depth[:5:5], ush02(strong), ush02(weak))},
+		{check: "history-depth", params: map[string]string{"size": "2"}, history: depth[:3], want: 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.check, func(t *testing.T) {
+			c, err := NewCheck(tc.check)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := c.Configure(tc.params); err != nil {
+				t.Fatal(err)
+			}
+			got := c.Check(Subject{History: tc.history})
+			if len(got) != tc.want {
+				t.Errorf("got %q, want %d findings", got, tc.want)
+			}
+			if tc.want > 0 && tc.msg != "" && got[0] != tc.msg {
+				t.Errorf("got %q, want %q", got[0], tc.msg)
+			}
+		})
+	}
+}
+
+func TestAddHistory(t *testing.T) {
+	a, err := New(Config{Checks: map[string]CheckConfig{"legacy-history": {}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := usr02.Record{Client: "001", User: "JDOE", Passcode: make(usr02.Passcode, usr02.PasscodeSize)}
+	a.AddHistory(usr02.Entry{Record: legacy})
+	testCases := []struct {
+		rec  usr02.Record
+		want int
+	}{
+		{rec: usr02.Record{Client: "001", User: "JDOE"}, want: 1},
+		{rec: usr02.Record{Client: "002", User: "JDOE"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.rec.Client, func(t *testing.T) {
+			var n int
+			for _, f := range a.Audit("PRD", tc.rec) {
+				if f.Check == "legacy-history" {
+					n++
+				}
+			}
+			if n != tc.want {
+				t.Errorf("got %d, want %d", n, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package audit
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/dim13/codvn"
+)
+
+// series of chart
+type series struct {
+	name   string
+	values []float64
+}
+
+var palette = []string{"#d62728", "#1f77b4", "#2ca02c", "#ff7f0e", "#9467bd"}
+
+// chart geometry
+const (
+	chartW, chartH = 640, 240
+	marginL        = 60
+	marginR        = 120
+	marginT        = 30
+	marginB        = 30
+)
+
+// lineChart renders runs as inline SVG, NaN values are left out
+func lineChart(title string, runs []Run, ss []series) template.HTML {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="11">`, chartW, chartH, chartW, chartH)
+	fmt.Fprintf(&b, `<text x="%d" y="18" font-size="13" font-weight="bold">%s</text>`, marginL, html.EscapeString(title))
+
+	max := 0.0
+	for _, s := range ss {
+		for _, v := range s.values {
+			if !math.IsNaN(v) {
+				max = math.Max(max, v)
+			}
+		}
+	}
+	if max == 0 {
+		max = 1
+	}
+	w, h := float64(chartW-marginL-marginR), float64(chartH-marginT-marginB)
+	first, last := runs[0].Time, runs[len(runs)-1].Time
+	x := func(i int) float64 {
+		span := last.Sub(first).Seconds()
+		if span == 0 {
+			return marginL + w/2
+		}
+		return marginL + w*runs[i].Time.Sub(first).Seconds()/span
+	}
+	y := func(v float64) float64 {
+		return marginT + h - h*v/max
+	}
+
+	// axes and grid
+	for i := 0; i <= 4; i++ {
+		v := max * float64(i) / 4
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#ddd"/>`, marginL, y(v), marginL+w, y(v))
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end">%s</text>`, marginL-6, y(v)+4, number(v))
+	}
+	fmt.Fprintf(&b, `<text x="%d" y="%d">%s</text>`, marginL, chartH-8, first.Format(dateLayout))
+	if len(runs) > 1 {
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="end">%s</text>`, marginL+w, chartH-8, last.Format(dateLayout))
+	}
+
+	for n, s := range ss {
+		color := palette[n%len(palette)]
+		// polyline per stretch of known values
+		var pts bytes.Buffer
+		for i, v := range append(s.values, math.NaN()) {
+			if !math.IsNaN(v) {
+				fmt.Fprintf(&pts, "%.1f,%.1f ", x(i), y(v))
+				continue
+			}
+			if pts.Len() > 0 {
+				fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="2" points="%s"/>`, color, pts.String())
+				pts.Reset()
+			}
+		}
+		for i, v := range s.values {
+			if math.IsNaN(v) {
+				continue
+			}
+			fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="3" fill="%s"><title>%s %s: %s</title></circle>`,
+				x(i), y(v), color, html.EscapeString(s.name), runs[i].Time.Format(dateLayout), number(v))
+		}
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%d" width="10" height="10" fill="%s"/>`, marginL+w+12, marginT+n*16, color)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d">%s</text>`, marginL+w+26, marginT+n*16+9, html.EscapeString(s.name))
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+func number(v float64) string {
+	if v == math.Trunc(v) {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.1f", v)
+}
+
+func values(runs []Run, f func(Run) float64) []float64 {
+	v := make([]float64, len(runs))
+	for i, r := range runs {
+		v[i] = f(r)
+	}
+	return v
+}
+
+// charts of system runs
+func charts(runs []Run) []template.HTML {
+	var kinds []series
+	for _, k := range []codvn.Kind{codvn.SHA1, codvn.SHA256, codvn.SHA384, codvn.SHA512} {
+		k := k
+		s := series{name: string(k), values: values(runs, func(r Run) float64 { return r.Share(k) })}
+		for _, v := range s.values {
+			if v > 0 {
+				kinds = append(kinds, s)
+				break
+			}
+		}
+	}
+	return []template.HTML{
+		lineChart("PWDSALTEDHASH algorithms, % of hashed users", runs, kinds),
+		lineChart("Median iterations", runs, []series{
+			{"median", values(runs, func(r Run) float64 { return float64(r.MedianIter) })},
+		}),
+		lineChart("Legacy columns, users", runs, []series{
+			{"BCODE", values(runs, func(r Run) float64 { return float64(r.Bcode) })},
+			{"PASSCODE", values(runs, func(r Run) float64 { return float64(r.Passcode) })},
+		}),
+		lineChart("Default password hits", runs, []series{
+			{"users", values(runs, func(r Run) float64 {
+				if !r.Ran("default-password") {
+					return math.NaN()
+				}
+				return float64(r.Findings["default-password"])
+			})},
+		}),
+	}
+}
+
+func total(m map[string]int) int {
+	var n int
+	for _, v := range m {
+		n += v
+	}
+	return n
+}
+
+var reportTmpl = template.Must(template.New("report").Funcs(template.FuncMap{"total": total}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Password hash hygiene</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+td, th { border: 1px solid #ccc; padding: 2px 8px; text-align: right; }
+svg { display: block; margin-bottom: 1em; }
+</style>
+</head>
+<body>
+<h1>Password hash hygiene</h1>
+{{range .}}
+<h2>{{.System}}</h2>
+<table>
+<tr><th>Date</th><th>Users</th><th>Median iterations</th><th>BCODE</th><th>PASSCODE</th><th>Findings</th></tr>
+{{range .Runs}}<tr><td>{{.Time.Format "2006-01-02"}}</td><td>{{.Users}}</td><td>{{.MedianIter}}</td><td>{{.Bcode}}</td><td>{{.Passcode}}</td><td>{{total .Findings}}</td></tr>
+{{end}}</table>
+{{range .Charts}}{{.}}
+{{end}}{{end}}
+</body>
+</html>
+`))
+
+// Report writes self-contained HTML report charting runs per system
+func Report(w io.Writer, runs []Run) error {
+	bySystem := make(map[string][]Run)
+	for _, r := range runs {
+		bySystem[r.System] = append(bySystem[r.System], r)
+	}
+	var systems []string
+	for s := range bySystem {
+		systems = append(systems, s)
+	}
+	sort.Strings(systems)
+	type section struct {
+		System string
+		Runs   []Run
+		Charts []template.HTML
+	}
+	var sections []section
+	for _, s := range systems {
+		sections = append(sections, section{System: s, Runs: bySystem[s], Charts: charts(bySystem[s])})
+	}
+	return reportTmpl.Execute(w, sections)
+}
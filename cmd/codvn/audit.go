@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dim13/codvn/audit"
+	"github.com/dim13/codvn/extract"
+	"github.com/dim13/codvn/usr02"
+)
+
+// auditDumps runs audit checks over USR02 dumps, or records extracted from
+// database, and prints findings. Database drivers are linked in by build
+// tags, see sqlite.go.
+func auditDumps(args []string) error {
+	fs := flag.NewFlagSet("audit", flag.ExitOnError)
+	system := fs.String("system", "", "system ID")
+	config := fs.String("config", "", "audit configuration `file`")
+	format := fs.String("format", "tsv", "dump format (tsv, csv)")
+	list := fs.Bool("list", false, "list checks and exit")
+	exceptions := fs.String("exceptions", "", "accepted risks `file`")
+	history := fs.String("history", "", "audit history `file` to record run")
+	ush02 := fs.String("ush02", "", "USH02 export `file` of password change documents")
+	pwdhistory := fs.String("pwdhistory", "", "USRPWDHISTORY export `file`")
+	driver := fs.String("driver", "", "database/sql `driver` to extract USR02 from, instead of dumps")
+	dsn := fs.String("dsn", "", "database `source` name")
+	dialect := fs.String("dialect", "hana", "database dialect (hana, oracle, mssql, ase, sqlite)")
+	schema := fs.String("schema", "", "database schema, default of dialect if empty")
+	clients := fs.String("clients", "000", "comma separated clients to extract")
+	query := fs.String("query", "", "query `template`, extract.DefaultQuery if empty")
+	table := fs.String("table", "", "table to extract from, USR02 if empty")
+	columns := fs.String("columns", "", "comma separated columns to extract, all known if empty")
+	fs.Parse(args)
+
+	var c audit.Config
+	if *config != "" {
+		var err error
+		if c, err = audit.LoadConfig(*config); err != nil {
+			return err
+		}
+	}
+	a, err := audit.New(c)
+	if err != nil {
+		return err
+	}
+	if *list {
+		return listChecks(os.Stdout, a)
+	}
+	if *system == "" || (fs.NArg() == 0) == (*driver == "") {
+		return errors.New("system and either dump files or database driver required")
+	}
+	comma, err := usr02.Comma(*format)
+	if err != nil {
+		return err
+	}
+	for _, h := range []struct{ table, path string }{
+		{usr02.TablePwdHistory, *pwdhistory},
+		{usr02.TableUSH02, *ush02},
+	} {
+		if h.path == "" {
+			continue
+		}
+		if err := loadHistory(a, h.path, h.table, comma); err != nil {
+			return fmt.Errorf("%s: %v", h.path, err)
+		}
+	}
+	now := time.Now()
+	a.Date = now
+	tally := audit.NewTally(*system, now, a.Enabled())
+	var findings []audit.Finding
+	for _, path := range fs.Args() {
+		fnd, err := auditFile(a, tally, *system, path, comma)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		findings = append(findings, fnd...)
+	}
+	if *driver != "" {
+		db, err := sql.Open(*driver, *dsn)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		e, err := extract.New(db, *dialect)
+		if err != nil {
+			return err
+		}
+		e.Schema, e.Table, e.Query = *schema, *table, *query
+		if *columns != "" {
+			e.Columns = strings.Split(*columns, ",")
+		}
+		fnd, err := auditDB(a, tally, *system, e, strings.Split(*clients, ","))
+		if err != nil {
+			return err
+		}
+		findings = append(findings, fnd...)
+	}
+	if *history != "" {
+		if err := audit.AppendRun(*history, tally.Run()); err != nil {
+			return err
+		}
+	}
+	if *exceptions != "" {
+		exc, err := audit.LoadExceptions(*exceptions)
+		if err != nil {
+			return err
+		}
+		var status []audit.ExceptionStatus
+		findings, status = audit.Suppress(findings, exc, now)
+		writeExceptions(os.Stderr, status)
+	}
+	return writeFindings(os.Stdout, findings)
+}
+
+func auditFile(a *audit.Auditor, tally *audit.Tally, system, path string, comma rune) ([]audit.Finding, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return a.Run(system, usr02.NewReader(bufio.NewReader(f), comma), tally.Add)
+}
+
+// auditDB runs audit over records of clients streamed from database
+func auditDB(a *audit.Auditor, tally *audit.Tally, system string, e *extract.Extractor, clients []string) ([]audit.Finding, error) {
+	var findings []audit.Finding
+	for _, client := range clients {
+		err := e.Extract(context.Background(), client, func(r usr02.Record) error {
+			f := a.Audit(system, r)
+			tally.Add(r, f)
+			findings = append(findings, f...)
+			return nil
+		})
+		if err != nil {
+			return findings, fmt.Errorf("client %s: %v", client, err)
+		}
+	}
+	return findings, nil
+}
+
+// loadHistory adds password history export of table to auditor
+func loadHistory(a *audit.Auditor, path, table string, comma rune) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	entries, err := usr02.NewHistoryReader(bufio.NewReader(f), comma).ReadAll()
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		entries[i].Table = table
+	}
+	a.AddHistory(entries...)
+	return nil
+}
+
+// writeExceptions reports expired and unused exceptions
+func writeExceptions(w io.Writer, status []audit.ExceptionStatus) {
+	for _, s := range status {
+		e := s.Exception
+		switch {
+		case s.Expired && s.Matched > 0:
+			fmt.Fprintf(w, "expired %s: %s %s/%s/%s, %d findings reported again\n", e.Expires, e.Check, e.System, e.Client, e.User, s.Matched)
+		case s.Expired:
+			fmt.Fprintf(w, "expired %s: %s %s/%s/%s\n", e.Expires, e.Check, e.System, e.Client, e.User)
+		case s.Unused():
+			fmt.Fprintf(w, "unused: %s %s/%s/%s, approved by %s\n", e.Check, e.System, e.Client, e.User, e.Approver)
+		}
+	}
+}
+
+// listChecks prints registered checks, enabled ones with effective
+// severity and parameters
+func listChecks(w io.Writer, a *audit.Auditor) error {
+	enabled := make(map[string]audit.Description)
+	for _, d := range a.Effective() {
+		enabled[d.ID] = d
+	}
+	for _, id := range audit.Checks() {
+		d, ok := enabled[id]
+		state := "enabled"
+		if !ok {
+			c, err := audit.NewCheck(id)
+			if err != nil {
+				return err
+			}
+			d, state = c.Describe(), "disabled"
+		}
+		fmt.Fprintf(w, "%-22s %-8s %-8s %s", d.ID, d.Severity, state, d.Title)
+		var keys []string
+		for k := range d.Params {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(w, " [%s=%s]", k, d.Params[k])
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+func writeFindings(w io.Writer, findings []audit.Finding) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "SEVERITY\tCHECK\tSYSTEM\tMANDT\tBNAME\tMESSAGE")
+	for _, f := range findings {
+		fmt.Fprintf(bw, "%s\t%s\t%s\t%s\t%s\t%s\n", f.Severity, f.Check, f.System, f.Client, f.User, f.Message)
+	}
+	return bw.Flush()
+}
+
+// trend writes HTML report of audit history
+func trend(args []string) error {
+	fs := flag.NewFlagSet("trend", flag.ExitOnError)
+	history := fs.String("history", "", "audit history `file`")
+	out := fs.String("o", "", "HTML report `file`")
+	fs.Parse(args)
+
+	if *history == "" || *out == "" {
+		return errors.New("history and report files required")
+	}
+	runs, err := audit.LoadRuns(*history)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := audit.Report(f, runs); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
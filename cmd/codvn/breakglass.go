@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dim13/codvn"
+	"github.com/dim13/codvn/shamir"
+	"github.com/dim13/codvn/strength"
+	"github.com/dim13/codvn/usr02"
+)
+
+// breakglass generates random password, keeps its hash only and prints
+// cleartext as k-of-n shares
+func breakglass(args []string) error {
+	fs := flag.NewFlagSet("breakglass", flag.ExitOnError)
+	client := fs.String("client", "000", "SAP client")
+	user := fs.String("user", "", "user name")
+	k := fs.Int("k", 2, "shares required to recombine")
+	n := fs.Int("n", 3, "shares to generate")
+	length := fs.Int("length", 24, "password length")
+	storePath := fs.String("store", "", "credential store `file` to update")
+	keys := fs.String("keys", "", "store keys `file`")
+	change := fs.String("change", "", "USR02 change `file` to write")
+	p := policyFlags(fs)
+	fs.Parse(args)
+
+	if *user == "" {
+		return errors.New("no user given")
+	}
+	if *storePath == "" && *change == "" {
+		return errors.New("neither store nor change file given")
+	}
+	pass, err := p.Generate(*length)
+	if err != nil {
+		return err
+	}
+	defer wipe(pass)
+	r := strength.Estimate(string(pass), *user)
+	fmt.Fprintf(os.Stderr, "strength score %d, about 2^%.0f guesses\n", r.Score, r.Entropy)
+	rec, err := p.Record(*client, *user, pass)
+	if err != nil {
+		return err
+	}
+	shares, err := shamir.Split(pass, *k, *n, rand.Reader)
+	if err != nil {
+		return err
+	}
+	if *storePath != "" {
+		s, err := openStore(*storePath, *keys)
+		if err != nil {
+			return err
+		}
+		e, err := s.Put(*client, *user, rec.PwdSaltedHash)
+		if err != nil {
+			return err
+		}
+		s.Log(e, "breakglass", fmt.Sprintf("%d of %d shares", *k, *n))
+		if err := s.Save(); err != nil {
+			return err
+		}
+	}
+	if *change != "" {
+		if err := writeChange(*change, rec); err != nil {
+			return err
+		}
+	}
+	for _, s := range shares {
+		fmt.Println(s)
+	}
+	return nil
+}
+
+func writeChange(path string, recs ...usr02.Record) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	w := usr02.NewChangeWriter(f)
+	for _, r := range recs {
+		if err := w.Write(r); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// recombine reads shares from arguments or stdin, one per line, and reveals
+// password only if it matches stored hash
+func recombine(args []string) error {
+	fs := flag.NewFlagSet("recombine", flag.ExitOnError)
+	client := fs.String("client", "000", "SAP client")
+	user := fs.String("user", "", "user name")
+	storePath := fs.String("store", "", "credential store `file`")
+	keys := fs.String("keys", "", "store keys `file`")
+	hashed := fs.String("hash", "", "stored PWDSALTEDHASH, instead of store")
+	p := policyFlags(fs)
+	fs.Parse(args)
+
+	var hash codvn.CodvN
+	switch {
+	case *hashed != "":
+		h, err := codvn.Parse([]byte(*hashed))
+		if err != nil {
+			return err
+		}
+		hash = h
+	case *storePath != "":
+		s, err := openStore(*storePath, *keys)
+		if err != nil {
+			return err
+		}
+		e, err := s.Get(*client, *user)
+		if err != nil {
+			return err
+		}
+		hash = e.Hash
+	default:
+		return errors.New("neither store nor hash given")
+	}
+
+	lines := fs.Args()
+	if len(lines) == 0 {
+		sc := bufio.NewScanner(os.Stdin)
+		for sc.Scan() {
+			if l := strings.TrimSpace(sc.Text()); l != "" {
+				lines = append(lines, l)
+			}
+		}
+		if err := sc.Err(); err != nil {
+			return err
+		}
+	}
+	var shares []shamir.Share
+	for _, l := range lines {
+		s, err := shamir.Parse([]byte(l))
+		if err != nil {
+			return err
+		}
+		shares = append(shares, s)
+	}
+	pass, err := shamir.Combine(shares)
+	if err != nil {
+		return err
+	}
+	defer wipe(pass)
+	if err := p.Verifier().Verify(hash, pass); err != nil {
+		return err
+	}
+	fmt.Printf("%s\n", pass)
+	return nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dim13/codvn/strength"
+)
+
+// check reads password from stdin, reports strength and policy violations
+func check(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	user := fs.String("user", "", "user name, treated as guessable input")
+	p := policyFlags(fs)
+	fs.Parse(args)
+
+	sc := bufio.NewScanner(os.Stdin)
+	if !sc.Scan() {
+		return sc.Err()
+	}
+	pass := sc.Bytes()
+	defer wipe(pass)
+	r := strength.Estimate(string(pass), *user)
+	fmt.Printf("score %d, about 2^%.0f guesses\n", r.Score, r.Entropy)
+	for _, w := range p.Explain(pass, *user) {
+		fmt.Println(" -", w)
+	}
+	return p.Check(pass, *user)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dim13/codvn/detect"
+)
+
+// detectAttacks reads authentication events, JSON lines, from files or
+// stdin and prints alerts on spraying, brute force and credential stuffing
+func detectAttacks(args []string) error {
+	fs := flag.NewFlagSet("detect", flag.ExitOnError)
+	c := detect.DefaultConfig
+	fs.DurationVar(&c.BruteForce.Window, "brute-window", c.BruteForce.Window, "brute force window")
+	fs.IntVar(&c.BruteForce.Threshold, "brute", c.BruteForce.Threshold, "failures of one user, 0 disables")
+	fs.DurationVar(&c.Spraying.Window, "spray-window", c.Spraying.Window, "spraying window")
+	fs.IntVar(&c.Spraying.Threshold, "spray", c.Spraying.Threshold, "users failed few times, 0 disables")
+	fs.IntVar(&c.SprayAttempts, "spray-attempts", c.SprayAttempts, "failures per user counting as few")
+	fs.DurationVar(&c.Stuffing.Window, "stuffing-window", c.Stuffing.Window, "credential stuffing window")
+	fs.IntVar(&c.Stuffing.Threshold, "stuffing", c.Stuffing.Threshold, "users failed with distinct passwords, 0 disables")
+	format := fs.String("format", "text", "alert format (text, json)")
+	fs.Parse(args)
+
+	bw := bufio.NewWriter(os.Stdout)
+	var emit func(detect.Alert) error
+	switch *format {
+	case "text":
+		emit = func(a detect.Alert) error {
+			fmt.Fprintln(bw, a)
+			return bw.Flush()
+		}
+	case "json":
+		enc := json.NewEncoder(bw)
+		emit = func(a detect.Alert) error {
+			if err := enc.Encode(a); err != nil {
+				return err
+			}
+			return bw.Flush()
+		}
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	d := detect.New(c)
+	if fs.NArg() == 0 {
+		return detect.Scan(os.Stdin, d, emit)
+	}
+	for _, path := range fs.Args() {
+		if err := detectFile(d, path, emit); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+func detectFile(d *detect.Detector, path string, emit func(detect.Alert) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return detect.Scan(bufio.NewReader(f), d, emit)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dim13/codvn/forensics"
+	"github.com/dim13/codvn/usr02"
+)
+
+// provenance clusters hashes of USR02 dumps by generation fingerprint and
+// lists users whose hashes likely weren't created by the kernel
+func provenance(args []string) error {
+	fs := flag.NewFlagSet("forensics", flag.ExitOnError)
+	format := fs.String("format", "tsv", "dump format (tsv, csv)")
+	minScore := fs.Int("minscore", 50, "minimal salt score")
+	all := fs.Bool("all", false, "list all users, not only suspect")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		return errors.New("dump files required")
+	}
+	comma, err := usr02.Comma(*format)
+	if err != nil {
+		return err
+	}
+	a := forensics.New()
+	a.MinScore = *minScore
+	for _, path := range fs.Args() {
+		if err := analyzeFile(a, path, comma); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	rep := a.Report()
+	bw := bufio.NewWriter(os.Stdout)
+	fmt.Fprintln(bw, "USERS\tSHARE\tFINGERPRINT")
+	for _, c := range rep.Clusters {
+		fmt.Fprintf(bw, "%d\t%.1f%%\t%v\n", c.Users, c.Share, c.Fingerprint)
+	}
+	fmt.Fprintln(bw)
+	fmt.Fprintln(bw, "MANDT\tBNAME\tSCORE\tFINGERPRINT\tREASONS")
+	for _, r := range rep.Results {
+		if *all || r.Suspect() {
+			fmt.Fprintf(bw, "%s\t%s\t%d\t%v\t%s\n", r.Client, r.User, r.Score, r.Fingerprint, strings.Join(r.Reasons, "; "))
+		}
+	}
+	return bw.Flush()
+}
+
+func analyzeFile(a *forensics.Analyzer, path string, comma rune) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := usr02.NewReader(bufio.NewReader(f), comma)
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		a.Add(rec)
+	}
+}
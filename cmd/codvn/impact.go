@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dim13/codvn/impact"
+	"github.com/dim13/codvn/policy"
+	"github.com/dim13/codvn/usr02"
+)
+
+// simulate reports consequences of proposed login profile parameters on
+// users of USR02 dumps
+func simulate(args []string) error {
+	fs := flag.NewFlagSet("impact", flag.ExitOnError)
+	current := fs.String("current", "", "current profile `file`")
+	proposed := fs.String("proposed", "", "proposed profile `file`")
+	format := fs.String("format", "tsv", "dump format (tsv, csv)")
+	recovered := fs.String("recovered", "", "recovered passwords `file` of MANDT, BNAME and PASSWORD separated by tabs, as synth answer key")
+	fs.Parse(args)
+
+	if *current == "" || *proposed == "" || fs.NArg() == 0 {
+		return errors.New("current and proposed profiles and dump files required")
+	}
+	cur, err := loadProfile(*current)
+	if err != nil {
+		return err
+	}
+	prop, err := loadProfile(*proposed)
+	if err != nil {
+		return err
+	}
+	comma, err := usr02.Comma(*format)
+	if err != nil {
+		return err
+	}
+	s := impact.New(cur, prop)
+	var list map[string]string
+	if *recovered != "" {
+		if list, err = readRecovered(*recovered); err != nil {
+			return err
+		}
+	}
+	s.Known = impact.Recovered(list)
+	for _, path := range fs.Args() {
+		if err := simulateFile(s, path, comma); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	rep := s.Report()
+	bw := bufio.NewWriter(os.Stdout)
+	fmt.Fprintf(bw, "%d users\n", rep.Users)
+	for _, i := range []impact.Impact{impact.Rehash, impact.DropLegacy, impact.ForcedChange, impact.Break} {
+		fmt.Fprintf(bw, "%-14s %d (%d new)\n", i, rep.Counts[i], rep.New[i])
+	}
+	fmt.Fprintln(bw)
+	fmt.Fprintln(bw, "MANDT\tBNAME\tUSTYP\tIMPACT\tNEW\tREASON")
+	for _, r := range rep.Results {
+		for _, e := range r.Effects {
+			fmt.Fprintf(bw, "%s\t%s\t%s\t%s\t%v\t%s\n", r.Client, r.User, r.Type, e.Impact, e.New, e.Reason)
+		}
+	}
+	return bw.Flush()
+}
+
+func loadProfile(path string) (policy.Policy, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return policy.Policy{}, err
+	}
+	defer f.Close()
+	p, err := policy.ParseProfile(f, policy.Kernel)
+	if err != nil {
+		return p, fmt.Errorf("%s: %v", path, err)
+	}
+	return p, nil
+}
+
+// readRecovered reads recovered passwords keyed by client and user
+func readRecovered(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	list := make(map[string]string)
+	s := bufio.NewScanner(f)
+	for line := 1; s.Scan(); line++ {
+		l := strings.TrimRight(s.Text(), "\r")
+		if l == "" || (line == 1 && strings.HasPrefix(l, "MANDT\t")) {
+			continue
+		}
+		v := strings.SplitN(l, "\t", 3)
+		if len(v) != 3 {
+			return nil, fmt.Errorf("%s:%d: want MANDT, BNAME and PASSWORD", path, line)
+		}
+		list[v[0]+"/"+v[1]] = v[2]
+	}
+	return list, s.Err()
+}
+
+func simulateFile(s *impact.Simulator, path string, comma rune) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := usr02.NewReader(bufio.NewReader(f), comma)
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		s.Add(rec)
+	}
+}
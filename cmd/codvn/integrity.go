@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/dim13/codvn/store"
+)
+
+// openStore opens credential store, authenticated by keys file if given
+func openStore(path, keys string) (*store.Store, error) {
+	s, err := store.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if keys != "" {
+		if s.Keys, err = store.LoadKeys(keys); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// keygen prints new store key, append it to keys file to rotate
+func keygen(args []string) error {
+	fs := flag.NewFlagSet("keygen", flag.ExitOnError)
+	id := fs.String("id", "", "key id")
+	fs.Parse(args)
+
+	if *id == "" {
+		return errors.New("no key id given")
+	}
+	k, err := store.NewKey(*id)
+	if err != nil {
+		return err
+	}
+	fmt.Println(k)
+	return nil
+}
+
+// integrity checks MAC of every store entry, with -rotate re-signs them
+// with current key
+func integrity(args []string) error {
+	fs := flag.NewFlagSet("integrity", flag.ExitOnError)
+	storePath := fs.String("store", "", "credential store `file`")
+	keys := fs.String("keys", "", "store keys `file`")
+	rotate := fs.Bool("rotate", false, "re-sign authentic entries with current key")
+	adopt := fs.Bool("adopt", false, "sign unsigned entries on rotation")
+	fs.Parse(args)
+
+	if *storePath == "" || *keys == "" {
+		return errors.New("store and keys required")
+	}
+	s, err := openStore(*storePath, *keys)
+	if err != nil {
+		return err
+	}
+	var errs []store.IntegrityError
+	if *rotate {
+		errs = s.Rotate(*adopt)
+		if err := s.Save(); err != nil {
+			return err
+		}
+	} else {
+		errs = s.Check()
+	}
+	for _, e := range errs {
+		fmt.Println(e)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d of %d entries failed integrity check", len(errs), len(s.Entries))
+	}
+	return nil
+}
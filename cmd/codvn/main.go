@@ -0,0 +1,83 @@
+// Command codvn manages SAP CODVN H password hashes
+//
+// Usage:
+//
+//	codvn <command> [flags]
+//
+// Run "codvn <command> -h" for command flags.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/dim13/codvn/policy"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{"breakglass", "generate break-glass password split into shares", breakglass},
+	{"recombine", "recombine shares and verify against stored hash", recombine},
+	{"check", "check password from stdin against policy and explain weaknesses", check},
+	{"keygen", "generate credential store key", keygen},
+	{"integrity", "check or rotate credential store entry MACs", integrity},
+	{"synth", "generate synthetic USR02 dump with answer key", synthDump},
+	{"rotate", "rotate technical user password", rotate},
+	{"unseal", "open password sealed by rotate", unseal},
+	{"audit", "run audit checks over USR02 dumps or database", auditDumps},
+	{"trend", "chart audit history as HTML report", trend},
+	{"tacacs", "serve TACACS+ authentication against credential store", tacacsServe},
+	{"forensics", "score salts and flag hashes not created by the kernel", provenance},
+	{"pwsync", "receive password changes over mutual TLS and queue change files", syncServe},
+	{"detect", "detect password spraying, brute force and stuffing in authentication events", detectAttacks},
+	{"impact", "simulate impact of login profile parameter changes on users", simulate},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\nCommands:\n", os.Args[0])
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", c.name, c.usage)
+	}
+	os.Exit(2)
+}
+
+// policyFlags registers hashing flags initialized from default policy
+func policyFlags(fs *flag.FlagSet) *policy.Policy {
+	p := policy.Default
+	fs.StringVar((*string)(&p.Kind), "kind", string(p.Kind), "hash `algorithm` (sha, SHA256, SHA384, SHA512)")
+	fs.IntVar(&p.Iter, "iter", p.Iter, "hash iterations")
+	fs.IntVar(&p.SaltSize, "saltsize", p.SaltSize, "salt size in bytes")
+	fs.IntVar(&p.MinScore, "minscore", p.MinScore, "minimal password strength score (0-4)")
+	fs.IntVar(&p.Compat, "compat", p.Compat, "downwards compatibility `level` (0-5), above 0 needs BCODE")
+	return &p
+}
+
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("codvn: ")
+	if len(os.Args) < 2 {
+		usage()
+	}
+	for _, c := range commands {
+		if c.name == os.Args[1] {
+			if err := c.run(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+	}
+	usage()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/dim13/codvn/pwsync"
+)
+
+// syncServe receives password changes over mutual TLS and queues change
+// files per target system
+func syncServe(args []string) error {
+	fs := flag.NewFlagSet("pwsync", flag.ExitOnError)
+	addr := fs.String("listen", "localhost:8443", "listen `address`")
+	config := fs.String("config", "", "targets configuration `file`")
+	queue := fs.String("queue", "", "change file queue `directory`")
+	cert := fs.String("cert", "", "server certificate `file`")
+	key := fs.String("key", "", "server key `file`")
+	ca := fs.String("ca", "", "CA certificate `file` of callers")
+	fs.Parse(args)
+
+	if *config == "" || *queue == "" || *cert == "" || *key == "" || *ca == "" {
+		return errors.New("config, queue, certificate, key and caller CA required")
+	}
+	c, err := pwsync.LoadConfig(*config)
+	if err != nil {
+		return err
+	}
+	if len(c.Targets) == 0 {
+		return errors.New("no targets configured")
+	}
+	crt, err := tls.LoadX509KeyPair(*cert, *key)
+	if err != nil {
+		return err
+	}
+	pem, err := ioutil.ReadFile(*ca)
+	if err != nil {
+		return err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return errors.New("no CA certificates found")
+	}
+	h := pwsync.New(c, pwsync.Queue{Dir: *queue})
+	h.ErrorLog = log.New(os.Stderr, "", log.LstdFlags)
+	srv := &http.Server{
+		Addr:      *addr,
+		Handler:   h,
+		TLSConfig: pwsync.TLSConfig(crt, pool),
+		ErrorLog:  h.ErrorLog,
+	}
+	return srv.ListenAndServeTLS("", "")
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/dim13/codvn/seal"
+)
+
+// rotate generates new password of technical user, writes USR02 change
+// file and password sealed for the consuming side, and records rotation in
+// store history
+func rotate(args []string) error {
+	fs := flag.NewFlagSet("rotate", flag.ExitOnError)
+	client := fs.String("client", "000", "SAP client")
+	user := fs.String("user", "", "user name")
+	length := fs.Int("length", 32, "password length")
+	storePath := fs.String("store", "", "credential store `file` to update")
+	keys := fs.String("keys", "", "store keys `file`")
+	change := fs.String("change", "", "USR02 change `file` to write")
+	recipient := fs.String("recipient", "", "RSA public key `file` of consuming side")
+	sealed := fs.String("sealed", "", "sealed password `file` to write")
+	note := fs.String("note", "", "consumer, e.g. RFC destination, recorded in history")
+	p := policyFlags(fs)
+	fs.Parse(args)
+
+	if *user == "" {
+		return errors.New("no user given")
+	}
+	if *storePath == "" || *change == "" || *recipient == "" || *sealed == "" {
+		return errors.New("store, change file, recipient and sealed file required")
+	}
+	data, err := ioutil.ReadFile(*recipient)
+	if err != nil {
+		return err
+	}
+	pub, err := seal.ParsePublicKey(data)
+	if err != nil {
+		return err
+	}
+	s, err := openStore(*storePath, *keys)
+	if err != nil {
+		return err
+	}
+
+	pass, err := p.Generate(*length)
+	if err != nil {
+		return err
+	}
+	defer wipe(pass)
+	rec, err := p.Record(*client, *user, pass)
+	if err != nil {
+		return err
+	}
+	box, err := seal.Seal(pub, pass, map[string]string{
+		"Client": rec.Client,
+		"User":   rec.User,
+		"Note":   *note,
+	})
+	if err != nil {
+		return err
+	}
+	e, err := s.Put(rec.Client, rec.User, rec.PwdSaltedHash)
+	if err != nil {
+		return err
+	}
+	s.Log(e, "rotate", *note)
+	// outputs are removed unless store is saved, else they would hand out
+	// an untracked password
+	if err := ioutil.WriteFile(*sealed, box, 0600); err != nil {
+		return err
+	}
+	if err := writeChange(*change, rec); err != nil {
+		os.Remove(*sealed)
+		return err
+	}
+	if err := s.Save(); err != nil {
+		os.Remove(*sealed)
+		os.Remove(*change)
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%s/%s rotated to version %d\n", e.Client, e.User, e.Version)
+	return nil
+}
+
+// unseal prints password sealed by rotate
+func unseal(args []string) error {
+	fs := flag.NewFlagSet("unseal", flag.ExitOnError)
+	keyPath := fs.String("key", "", "RSA private key `file`")
+	fs.Parse(args)
+
+	if *keyPath == "" || fs.NArg() != 1 {
+		return errors.New("private key and sealed file required")
+	}
+	data, err := ioutil.ReadFile(*keyPath)
+	if err != nil {
+		return err
+	}
+	priv, err := seal.ParsePrivateKey(data)
+	if err != nil {
+		return err
+	}
+	if data, err = ioutil.ReadFile(fs.Arg(0)); err != nil {
+		return err
+	}
+	pass, headers, err := seal.Open(priv, data)
+	if err != nil {
+		return err
+	}
+	defer wipe(pass)
+	fmt.Fprintf(os.Stderr, "%s/%s %s\n", headers["Client"], headers["User"], headers["Note"])
+	fmt.Printf("%s\n", pass)
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build sqlite
+// +build sqlite
+
+package main
+
+// SQLite driver for audit of database copies, build with -tags sqlite,
+// needs cgo. Other drivers are linked in the same way.
+import _ "github.com/mattn/go-sqlite3"
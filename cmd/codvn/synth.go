@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dim13/codvn/synth"
+	"github.com/dim13/codvn/usr02"
+)
+
+// synthDump writes synthetic USR02 dump and answer key of known passwords
+func synthDump(args []string) error {
+	fs := flag.NewFlagSet("synth", flag.ExitOnError)
+	c := synth.Config{}
+	fs.Int64Var(&c.Seed, "seed", 1, "random seed")
+	fs.IntVar(&c.Users, "users", 10000, "users per client")
+	clients := fs.String("clients", "000,001", "comma separated clients")
+	fs.Float64Var(&c.Weak, "weak", 0.05, "fraction of users with known weak password")
+	fs.IntVar(&c.MaxIter, "maxiter", 0, "cap iterations of known hashes")
+	now := fs.String("now", synth.Epoch.Format("20060102"), "reference date `YYYYMMDD` of logon and change dates")
+	format := fs.String("format", "tsv", "dump format (tsv, csv)")
+	out := fs.String("o", "", "dump `file`")
+	keyPath := fs.String("key", "", "answer key `file`")
+	fs.Parse(args)
+
+	if *out == "" || *keyPath == "" {
+		return errors.New("dump and answer key files required")
+	}
+	comma, err := usr02.Comma(*format)
+	if err != nil {
+		return err
+	}
+	c.Clients = strings.Split(*clients, ",")
+	if c.Now, err = time.Parse("20060102", *now); err != nil {
+		return err
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	bw := bufio.NewWriter(f)
+	w := usr02.NewWriter(bw, comma)
+	key, err := synth.New(c).Generate(w.Write)
+	if err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	k, err := os.OpenFile(*keyPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	kw := bufio.NewWriter(k)
+	fmt.Fprintf(kw, "MANDT\tBNAME\tPASSWORD\n")
+	for _, a := range key {
+		fmt.Fprintf(kw, "%s\t%s\t%s\n", a.Client, a.User, a.Password)
+	}
+	if err := kw.Flush(); err != nil {
+		return err
+	}
+	return k.Close()
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"time"
+
+	"github.com/dim13/codvn/store"
+	"github.com/dim13/codvn/tacacs"
+)
+
+// tacacsServe runs TACACS+ authentication against credential store
+func tacacsServe(args []string) error {
+	fs := flag.NewFlagSet("tacacs", flag.ExitOnError)
+	addr := fs.String("listen", ":49", "listen `address`")
+	client := fs.String("client", "000", "SAP client of users")
+	storePath := fs.String("store", "", "credential store `file`")
+	keys := fs.String("keys", "", "store keys `file`")
+	secret := fs.String("secret", "", "shared secret `file`")
+	timeout := fs.Duration("timeout", time.Minute, "read timeout per packet")
+	p := policyFlags(fs)
+	fs.Parse(args)
+
+	if *storePath == "" || *secret == "" {
+		return errors.New("store and shared secret required")
+	}
+	key, err := ioutil.ReadFile(*secret)
+	if err != nil {
+		return err
+	}
+	s, err := openStore(*storePath, *keys)
+	if err != nil {
+		return err
+	}
+	s.Verifier = p.Verifier()
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	srv := &tacacs.Server{
+		Key:      bytes.TrimSpace(key),
+		Verifier: store.Verifier(s, *client),
+		Timeout:  *timeout,
+		ErrorLog: log.New(os.Stderr, "", log.LstdFlags),
+	}
+	return srv.Serve(l)
+}
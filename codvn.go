@@ -36,6 +36,7 @@ var (
 	ErrZeroIterations = errors.New("zero iterations")
 	ErrTruncatedInput = errors.New("truncated input")
 	ErrDontMatch      = errors.New("password doesn't match")
+	ErrPolicy         = errors.New("hash violates policy")
 )
 
 // Kind of password
@@ -114,7 +115,7 @@ func (c CodvN) String() string {
 }
 
 // MarshalText encodes password
-func (c *CodvN) MarshalText() (text []byte, err error) {
+func (c CodvN) MarshalText() (text []byte, err error) {
 	return []byte(c.String()), nil
 }
 
@@ -0,0 +1,320 @@
+// Package detect finds password spraying, brute force and credential
+// stuffing in authentication events
+//
+// Events are evaluated per source in sliding windows of event time, so the
+// same detector serves live streams and stored logs. Events of a source must
+// arrive in time order, merged logs need to be sorted first. Users are told
+// apart by client and name. Rules:
+//   - brute force: one user fails many times from a source
+//   - spraying: many users fail few times each from a source
+//   - credential stuffing: many users fail from a source, each with a
+//     password tried on no other user
+//
+// Stuffing and spraying look alike without knowing the passwords tried.
+// Front ends may add a keyed password fingerprint to events, see
+// Fingerprint; stuffing is only detected on events carrying one.
+package detect
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// Event of authentication
+type Event struct {
+	Time   time.Time `json:"time"`
+	Source string    `json:"source"` // client address
+	Client string    `json:"client,omitempty"`
+	User   string    `json:"user"`
+	OK     bool      `json:"ok"`
+	Secret string    `json:"secret,omitempty"` // password fingerprint
+}
+
+// Fingerprint of tried password, keyed so it can't be brute forced offline
+func Fingerprint(key, pass []byte) string {
+	m := hmac.New(sha256.New, key)
+	m.Write(pass)
+	return hex.EncodeToString(m.Sum(nil)[:8])
+}
+
+// Pattern of attack
+type Pattern string
+
+// Patterns
+const (
+	BruteForce Pattern = "brute-force"
+	Spraying   Pattern = "spraying"
+	Stuffing   Pattern = "stuffing"
+)
+
+// Rule fires when Threshold is reached within Window, zero Threshold
+// disables rule
+type Rule struct {
+	Window    time.Duration
+	Threshold int
+}
+
+// Config of detector
+type Config struct {
+	BruteForce    Rule // failures of one user from source
+	Spraying      Rule // users failed at most SprayAttempts times from source
+	SprayAttempts int
+	Stuffing      Rule // users failed with password used for no other user
+}
+
+// DefaultConfig catches slow sprays over a day
+var DefaultConfig = Config{
+	BruteForce:    Rule{Window: time.Hour, Threshold: 10},
+	Spraying:      Rule{Window: 24 * time.Hour, Threshold: 20},
+	SprayAttempts: 3,
+	Stuffing:      Rule{Window: time.Hour, Threshold: 20},
+}
+
+// Alert of detected attack
+type Alert struct {
+	Time      time.Time `json:"time"`
+	Pattern   Pattern   `json:"pattern"`
+	Source    string    `json:"source"`
+	Client    string    `json:"client,omitempty"` // target of brute force
+	User      string    `json:"user,omitempty"`
+	Users     int       `json:"users"`
+	Attempts  int       `json:"attempts"`
+	Failures  int       `json:"failures"`
+	Successes int       `json:"successes"`
+	Since     time.Time `json:"since"` // first event in window
+}
+
+func (a Alert) String() string {
+	if a.User != "" {
+		user := a.User
+		if a.Client != "" {
+			user = a.Client + "/" + user
+		}
+		return fmt.Sprintf("%s %s from %s against %s: %d failures since %s",
+			a.Time.Format(time.RFC3339), a.Pattern, a.Source, user, a.Failures, a.Since.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("%s %s from %s: %d users, %d attempts, %d successes since %s",
+		a.Time.Format(time.RFC3339), a.Pattern, a.Source, a.Users, a.Attempts, a.Successes, a.Since.Format(time.RFC3339))
+}
+
+// user of event, same name in other client is other user
+func (e Event) user() string {
+	return e.Client + "\x00" + e.User
+}
+
+type stat struct {
+	attempts, failures int
+}
+
+// window of events from one source
+type window struct {
+	d        time.Duration
+	limit    int                       // spray attempts
+	events   []Event                   // in time order
+	users    map[string]*stat          // by client and user
+	secrets  map[string]map[string]int // failures by secret and user
+	few      int                       // users with 1 to limit failures
+	unique   int                       // secrets tried on single user
+	failures int
+}
+
+func newWindow(d time.Duration, limit int) *window {
+	return &window{
+		d:       d,
+		limit:   limit,
+		users:   make(map[string]*stat),
+		secrets: make(map[string]map[string]int),
+	}
+}
+
+func (w *window) isFew(failures int) int {
+	if failures > 0 && failures <= w.limit {
+		return 1
+	}
+	return 0
+}
+
+func single(users int) int {
+	if users == 1 {
+		return 1
+	}
+	return 0
+}
+
+// update adds (n=1) or removes (n=-1) event
+func (w *window) update(e Event, n int) {
+	user := e.user()
+	s, ok := w.users[user]
+	if !ok {
+		s = new(stat)
+		w.users[user] = s
+	}
+	before := s.failures
+	s.attempts += n
+	if !e.OK {
+		s.failures += n
+		w.failures += n
+	}
+	w.few += w.isFew(s.failures) - w.isFew(before)
+	if s.attempts == 0 {
+		delete(w.users, user)
+	}
+	if e.Secret == "" || e.OK {
+		return
+	}
+	u, ok := w.secrets[e.Secret]
+	if !ok {
+		u = make(map[string]int)
+		w.secrets[e.Secret] = u
+	}
+	users := len(u)
+	if u[user] += n; u[user] == 0 {
+		delete(u, user)
+	}
+	w.unique += single(len(u)) - single(users)
+	if len(u) == 0 {
+		delete(w.secrets, e.Secret)
+	}
+}
+
+func (w *window) add(e Event) {
+	w.events = append(w.events, e)
+	w.update(e, 1)
+}
+
+// expire removes events older than window, events are expected in time
+// order
+func (w *window) expire(now time.Time) {
+	i := 0
+	for ; i < len(w.events) && now.Sub(w.events[i].Time) >= w.d; i++ {
+		w.update(w.events[i], -1)
+	}
+	w.events = w.events[i:]
+}
+
+func (w *window) alert(p Pattern, e Event) Alert {
+	return Alert{
+		Time:      e.Time,
+		Pattern:   p,
+		Source:    e.Source,
+		Users:     len(w.users),
+		Attempts:  len(w.events),
+		Failures:  w.failures,
+		Successes: len(w.events) - w.failures,
+		Since:     w.events[0].Time,
+	}
+}
+
+// Detector of attacks
+type Detector struct {
+	Config
+	sources map[string]map[time.Duration]*window
+	alerted map[string]time.Time
+	swept   time.Time
+}
+
+// New detector
+func New(c Config) *Detector {
+	return &Detector{
+		Config:  c,
+		sources: make(map[string]map[time.Duration]*window),
+		alerted: make(map[string]time.Time),
+	}
+}
+
+func (d *Detector) rules() map[Pattern]Rule {
+	return map[Pattern]Rule{
+		BruteForce: d.BruteForce,
+		Spraying:   d.Spraying,
+		Stuffing:   d.Stuffing,
+	}
+}
+
+func (d *Detector) maxWindow() time.Duration {
+	var max time.Duration
+	for _, r := range d.rules() {
+		if r.Threshold > 0 && r.Window > max {
+			max = r.Window
+		}
+	}
+	return max
+}
+
+// Add event and return alerts it raises. Events of a source must be added
+// in time order, earlier events than last one are not expired correctly.
+func (d *Detector) Add(e Event) []Alert {
+	d.sweep(e.Time)
+	windows, ok := d.sources[e.Source]
+	if !ok {
+		windows = make(map[time.Duration]*window)
+		d.sources[e.Source] = windows
+	}
+	for _, r := range d.rules() {
+		if r.Threshold > 0 && windows[r.Window] == nil {
+			windows[r.Window] = newWindow(r.Window, d.SprayAttempts)
+		}
+	}
+	for _, w := range windows {
+		w.expire(e.Time)
+		w.add(e)
+	}
+	var alerts []Alert
+	if r := d.BruteForce; r.Threshold > 0 && !e.OK {
+		w := windows[r.Window]
+		if s := w.users[e.user()]; s.failures >= r.Threshold {
+			a := w.alert(BruteForce, e)
+			a.Client, a.User, a.Users, a.Failures = e.Client, e.User, 1, s.failures
+			alerts = d.raise(alerts, a, r)
+		}
+	}
+	if r := d.Spraying; r.Threshold > 0 {
+		w := windows[r.Window]
+		// mostly distinct passwords per user are stuffing rather than spraying
+		if w.few >= r.Threshold && w.unique*2 < w.few {
+			alerts = d.raise(alerts, w.alert(Spraying, e), r)
+		}
+	}
+	if r := d.Stuffing; r.Threshold > 0 {
+		if w := windows[r.Window]; w.unique >= r.Threshold {
+			alerts = d.raise(alerts, w.alert(Stuffing, e), r)
+		}
+	}
+	return alerts
+}
+
+// raise alert unless raised for same source and target within rule window
+func (d *Detector) raise(alerts []Alert, a Alert, r Rule) []Alert {
+	key := string(a.Pattern) + "\x00" + a.Source + "\x00" + a.Client + "\x00" + a.User
+	if last, ok := d.alerted[key]; ok && a.Time.Sub(last) < r.Window {
+		return alerts
+	}
+	d.alerted[key] = a.Time
+	return append(alerts, a)
+}
+
+// sweep drops idle sources and stale alert state once per longest window
+func (d *Detector) sweep(now time.Time) {
+	max := d.maxWindow()
+	if now.Sub(d.swept) < max {
+		return
+	}
+	d.swept = now
+	for src, windows := range d.sources {
+		empty := true
+		for _, w := range windows {
+			w.expire(now)
+			empty = empty && len(w.events) == 0
+		}
+		if empty {
+			delete(d.sources, src)
+		}
+	}
+	for key, last := range d.alerted {
+		if now.Sub(last) >= max {
+			delete(d.alerted, key)
+		}
+	}
+}
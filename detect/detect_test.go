@@ -0,0 +1,106 @@
+package detect
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// attempts of users from source, every step, with password fingerprint
+// given by secret
+func attempts(source string, users, each int, step time.Duration, ok bool, secret func(user int) string) []Event {
+	var events []Event
+	t := start
+	for i := 0; i < each; i++ {
+		for u := 0; u < users; u++ {
+			events = append(events, Event{
+				Time:   t,
+				Source: source,
+				User:   fmt.Sprintf("USER%03d", u),
+				OK:     ok,
+				Secret: secret(u),
+			})
+			t = t.Add(step)
+		}
+	}
+	return events
+}
+
+func none(int) string     { return "" }
+func welcome1(int) string { return Fingerprint([]byte("key"), []byte("Welcome1")) }
+func leaked(u int) string { return Fingerprint([]byte("key"), []byte(fmt.Sprint("leaked", u))) }
+func patterns(a []Alert) map[Pattern]int {
+	m := make(map[Pattern]int)
+	for _, v := range a {
+		m[v.Pattern]++
+	}
+	return m
+}
+
+func TestDetector(t *testing.T) {
+	testCases := []struct {
+		name   string
+		events []Event
+		want   map[Pattern]int
+	}{
+		{name: "brute force", events: attempts("10.0.0.1", 1, 10, time.Minute, false, none), want: map[Pattern]int{BruteForce: 1}},
+		{name: "brute force below threshold", events: attempts("10.0.0.1", 1, 9, time.Minute, false, none), want: map[Pattern]int{}},
+		{name: "slow brute force", events: attempts("10.0.0.1", 1, 10, 10*time.Minute, false, none), want: map[Pattern]int{}},
+		{name: "brute force alerted once", events: attempts("10.0.0.1", 1, 50, time.Second, false, none), want: map[Pattern]int{BruteForce: 1}},
+		{name: "slow spray", events: attempts("10.0.0.2", 25, 1, 30*time.Minute, false, welcome1), want: map[Pattern]int{Spraying: 1}},
+		{name: "spray without fingerprints", events: attempts("10.0.0.2", 25, 2, 10*time.Minute, false, none), want: map[Pattern]int{Spraying: 1}},
+		{name: "stuffing", events: attempts("10.0.0.3", 25, 1, 10*time.Second, false, leaked), want: map[Pattern]int{Stuffing: 1}},
+		{name: "gateway logons", events: attempts("10.0.0.4", 25, 4, time.Minute, true, leaked), want: map[Pattern]int{}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := New(DefaultConfig)
+			var alerts []Alert
+			for _, e := range tc.events {
+				alerts = append(alerts, d.Add(e)...)
+			}
+			got := patterns(alerts)
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+			for p, n := range tc.want {
+				if got[p] != n {
+					t.Errorf("got %v, want %v", got, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestExpire(t *testing.T) {
+	d := New(DefaultConfig)
+	for _, e := range attempts("10.0.0.1", 1, 5, time.Minute, false, none) {
+		d.Add(e)
+	}
+	d.Add(Event{Time: start.Add(48 * time.Hour), Source: "10.0.0.9", User: "OTHER", OK: true})
+	if len(d.sources) != 1 {
+		t.Errorf("got %d sources, want idle source dropped", len(d.sources))
+	}
+}
+
+func TestClients(t *testing.T) {
+	d := New(DefaultConfig)
+	var alerts []Alert
+	// same user name in two clients, below threshold in each
+	for i, e := range attempts("10.0.0.1", 1, 10, time.Minute, false, none) {
+		e.Client = []string{"100", "200"}[i%2]
+		alerts = append(alerts, d.Add(e)...)
+	}
+	if len(alerts) != 0 {
+		t.Fatalf("got %v, want none", alerts)
+	}
+	for _, e := range attempts("10.0.0.1", 1, 5, time.Minute, false, none) {
+		e.Time, e.Client = e.Time.Add(10*time.Minute), "100"
+		alerts = append(alerts, d.Add(e)...)
+	}
+	if len(alerts) != 1 || alerts[0].Client != "100" || alerts[0].Failures != 10 {
+		t.Errorf("got %v, want brute force against 100/USER000", alerts)
+	}
+}
@@ -0,0 +1,34 @@
+package detect
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// MaxLine limits size of event line
+const MaxLine = 64 * 1024
+
+// Scan reads JSON lines of events from r, a stored log or live stream, and
+// passes raised alerts to fn until r ends. Events of a source are expected
+// in time order.
+func Scan(r io.Reader, d *Detector, fn func(Alert) error) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 4096), MaxLine)
+	for line := 1; s.Scan(); line++ {
+		if len(s.Bytes()) == 0 {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		for _, a := range d.Add(e) {
+			if err := fn(a); err != nil {
+				return err
+			}
+		}
+	}
+	return s.Err()
+}
@@ -0,0 +1,41 @@
+package detect
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScan(t *testing.T) {
+	testCases := []struct {
+		name    string
+		log     string
+		alerts  int
+		wantErr bool
+	}{
+		{
+			name: "brute force",
+			log: strings.Repeat(`{"time":"2026-03-01T10:00:00Z","source":"10.0.0.1","user":"DDIC","ok":false}`+"\n", 3) +
+				"\n" + `{"time":"2026-03-01T10:01:00Z","source":"10.0.0.1","user":"DDIC","ok":true}` + "\n",
+			alerts: 1,
+		},
+		{name: "empty"},
+		{name: "malformed", log: "{\"time\":\n", wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := DefaultConfig
+			c.BruteForce.Threshold = 3
+			var got []Alert
+			err := Scan(strings.NewReader(tc.log), New(c), func(a Alert) error {
+				got = append(got, a)
+				return nil
+			})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got %v, want error %v", err, tc.wantErr)
+			}
+			if len(got) != tc.alerts {
+				t.Errorf("got %v, want %d alerts", got, tc.alerts)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+// Package extract streams USR02 records directly from SAP databases
+//
+// Any database/sql driver is usable, programs import the driver of their
+// database. Dialects know identifier quoting, placeholders and the usual
+// schema of each database type, the query is a template over them:
+//
+//	SELECT {{.Columns}} FROM {{.Table}} WHERE {{.Client}} = {{.Param}}
+//
+// Each configured client is queried with a bound parameter, result columns
+// are matched by name to USR02 columns.
+//
+// The SQLite driver required by go.mod is used by tests and the sqlite
+// build of cmd/codvn only. It needs cgo, its tests are skipped without.
+package extract
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/dim13/codvn/usr02"
+)
+
+// Errors
+var (
+	ErrDialect = errors.New("unknown dialect")
+)
+
+// Dialect of database
+type Dialect struct {
+	Name   string
+	Schema string                 // default schema, e.g. SAPSR3
+	Quote  func(id string) string // quotes identifier
+	Param  func(n int) string     // n-th placeholder, starting at 1
+}
+
+func doubleQuote(id string) string { return `"` + id + `"` }
+func bracket(id string) string     { return "[" + id + "]" }
+func bare(id string) string        { return id }
+func question(int) string          { return "?" }
+
+// Dialects by name
+var Dialects = map[string]Dialect{
+	"hana":   {Name: "hana", Schema: "SAPHANADB", Quote: doubleQuote, Param: question},
+	"oracle": {Name: "oracle", Schema: "SAPSR3", Quote: doubleQuote, Param: func(n int) string { return ":" + strconv.Itoa(n) }},
+	"mssql":  {Name: "mssql", Quote: bracket, Param: func(n int) string { return "@p" + strconv.Itoa(n) }},
+	"ase":    {Name: "ase", Schema: "SAPSR3", Quote: bare, Param: question},
+	"sqlite": {Name: "sqlite", Quote: doubleQuote, Param: question},
+}
+
+// DefaultQuery selects all known USR02 columns of a client
+const DefaultQuery = `SELECT {{.Columns}} FROM {{.Table}} WHERE {{.Client}} = {{.Param}}`
+
+// Extractor of USR02 records
+type Extractor struct {
+	DB      *sql.DB
+	Dialect Dialect
+	Schema  string   // overrides dialect schema, MSSQL uses lower case SID
+	Table   string   // USR02 if empty
+	Query   string   // query template, DefaultQuery if empty
+	Columns []string // selected columns, all known if empty
+}
+
+// New extractor of named dialect
+func New(db *sql.DB, dialect string) (*Extractor, error) {
+	d, ok := Dialects[dialect]
+	if !ok {
+		return nil, ErrDialect
+	}
+	return &Extractor{DB: db, Dialect: d}, nil
+}
+
+// query renders query template
+func (e *Extractor) query() (string, error) {
+	q := e.Query
+	if q == "" {
+		q = DefaultQuery
+	}
+	t, err := template.New("query").Parse(q)
+	if err != nil {
+		return "", err
+	}
+	cols := e.Columns
+	if len(cols) == 0 {
+		cols = usr02.Columns()
+	}
+	quoted := make([]string, len(cols))
+	for i, c := range cols {
+		quoted[i] = e.Dialect.Quote(c)
+	}
+	table := e.Table
+	if table == "" {
+		table = "USR02"
+	}
+	table = e.Dialect.Quote(table)
+	schema := e.Schema
+	if schema == "" {
+		schema = e.Dialect.Schema
+	}
+	if schema != "" {
+		table = e.Dialect.Quote(schema) + "." + table
+	}
+	var b bytes.Buffer
+	err = t.Execute(&b, struct {
+		Columns, Table, Client, Param string
+	}{
+		Columns: strings.Join(quoted, ", "),
+		Table:   table,
+		Client:  e.Dialect.Quote("MANDT"),
+		Param:   e.Dialect.Param(1),
+	})
+	return b.String(), err
+}
+
+// Extract records of client, calling fn for each while rows are streamed
+func (e *Extractor) Extract(ctx context.Context, client string, fn func(usr02.Record) error) error {
+	q, err := e.query()
+	if err != nil {
+		return err
+	}
+	rows, err := e.DB.QueryContext(ctx, q, client)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	names, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	values := make([]interface{}, len(names))
+	ptrs := make([]interface{}, len(names))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		var r usr02.Record
+		for i, name := range names {
+			name = strings.ToUpper(name)
+			if err := r.Set(name, text(name, values[i])); err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// text converts database value to its USR02 text form, RAW legacy hashes
+// are hex encoded
+func text(name string, v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		if (name == "BCODE" && len(v) == usr02.BcodeSize) || (name == "PASSCODE" && len(v) == usr02.PasscodeSize) {
+			return hex.EncodeToString(v)
+		}
+		return string(v)
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		return v.Format("20060102")
+	}
+	return fmt.Sprint(v)
+}
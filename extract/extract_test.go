@@ -0,0 +1,37 @@
+package extract
+
+import "testing"
+
+func TestDialects(t *testing.T) {
+	testCases := []struct {
+		dialect string
+		schema  string
+		want    string
+	}{
+		{dialect: "hana", want: `SELECT "MANDT", "BNAME" FROM "SAPHANADB"."USR02" WHERE "MANDT" = ?`},
+		{dialect: "oracle", want: `SELECT "MANDT", "BNAME" FROM "SAPSR3"."USR02" WHERE "MANDT" = :1`},
+		{dialect: "mssql", schema: "prd", want: `SELECT [MANDT], [BNAME] FROM [prd].[USR02] WHERE [MANDT] = @p1`},
+		{dialect: "ase", want: `SELECT MANDT, BNAME FROM SAPSR3.USR02 WHERE MANDT = ?`},
+		{dialect: "sqlite", want: `SELECT "MANDT", "BNAME" FROM "USR02" WHERE "MANDT" = ?`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.dialect, func(t *testing.T) {
+			e, err := New(nil, tc.dialect)
+			if err != nil {
+				t.Fatal(err)
+			}
+			e.Schema = tc.schema
+			e.Columns = []string{"MANDT", "BNAME"}
+			got, err := e.query()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+	if _, err := New(nil, "db2"); err != ErrDialect {
+		t.Errorf("got %v, want %v", err, ErrDialect)
+	}
+}
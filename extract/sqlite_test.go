@@ -0,0 +1,102 @@
+//go:build cgo
+// +build cgo
+
+package extract
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/dim13/codvn/usr02"
+)
+
+const testHash = `{x-issha,1024}IlU5JC/UaAzvUl8ncaxIBlFQ1Nfd0C5YxkizRFg970g=`
+
+func testDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	stmts := []string{
+		`CREATE TABLE USR02 (
+			MANDT TEXT, BNAME TEXT, USTYP TEXT, CODVN TEXT,
+			BCODE BLOB, PASSCODE BLOB, PWDSALTEDHASH TEXT,
+			UFLAG INTEGER, PWDSTATE INTEGER, LOCNT INTEGER,
+			TRDAT TEXT, PWDCHGDATE TEXT, GLTGB TEXT)`,
+		`INSERT INTO USR02 VALUES ('100', 'SAP*', 'A', 'I', x'0102030405060708', x'0102030405060708090a0b0c0d0e0f1011121314', '` + testHash + `', 0, 0, 0, '20240131', '20230101', '00000000')`,
+		`INSERT INTO USR02 VALUES ('100', 'RFC_USER', 'C', 'B', x'0807060504030201', NULL, NULL, 64, 1, 3, '00000000', '20200202', '00000000')`,
+		`INSERT INTO USR02 VALUES ('200', 'DDIC', 'A', 'H', NULL, NULL, '` + testHash + `', 0, 0, 0, '00000000', '00000000', '00000000')`,
+	}
+	for _, s := range stmts {
+		if _, err := db.Exec(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestExtract(t *testing.T) {
+	db := testDB(t)
+	defer db.Close()
+	e, err := New(db, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Schema = "main"
+	var recs []usr02.Record
+	err = e.Extract(context.Background(), "100", func(r usr02.Record) error {
+		recs = append(recs, r)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 2 {
+		t.Fatalf("got %d records, want 2", len(recs))
+	}
+	sap, rfc := recs[0], recs[1]
+	if sap.User != "SAP*" || !sap.HasHash() || sap.PwdSaltedHash.String() != testHash ||
+		sap.Bcode.String() != "0102030405060708" || sap.Passcode.IsZero() ||
+		!sap.LastLogon.Equal(time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("got %+v", sap)
+	}
+	if rfc.HasHash() || rfc.Lock != usr02.LockAdmin || rfc.PwdState != usr02.PwdInitial || rfc.Failed != 3 || !rfc.LastLogon.IsZero() {
+		t.Errorf("got %+v", rfc)
+	}
+
+	// callback error stops extraction
+	stop := errors.New("stop")
+	if err := e.Extract(context.Background(), "100", func(usr02.Record) error { return stop }); err != stop {
+		t.Errorf("got %v, want %v", err, stop)
+	}
+}
+
+func TestCustomQuery(t *testing.T) {
+	db := testDB(t)
+	defer db.Close()
+	e, err := New(db, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Columns = []string{"MANDT", "BNAME", "PWDSALTEDHASH"}
+	e.Query = DefaultQuery + ` AND PWDSALTEDHASH IS NOT NULL`
+	var users []string
+	for _, client := range []string{"100", "200"} {
+		err := e.Extract(context.Background(), client, func(r usr02.Record) error {
+			users = append(users, r.Client+"/"+r.User)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(users) != 2 || users[0] != "100/SAP*" || users[1] != "200/DDIC" {
+		t.Errorf("got %v", users)
+	}
+}
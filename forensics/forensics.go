@@ -0,0 +1,264 @@
+// Package forensics analyses salts and parameters of CODVN H hashes to tell
+// hashes generated by the SAP kernel from those of third-party tools
+//
+// The kernel draws salts from a random source and uses configured
+// iterations and salt sizes, so all hashes of a system share few
+// generation fingerprints with random salts. Provisioning tools often
+// produce low entropy, sequential, repeated or shared salts and odd
+// parameters.
+package forensics
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/dim13/codvn"
+	"github.com/dim13/codvn/usr02"
+)
+
+// Pattern of salt
+type Pattern string
+
+// Patterns, ordered by weakness
+const (
+	Random     Pattern = "random"
+	LowEntropy Pattern = "low-entropy"
+	ASCII      Pattern = "ascii"
+	Sequential Pattern = "sequential"
+	Repeated   Pattern = "repeated"
+	Constant   Pattern = "constant"
+	Empty      Pattern = "empty"
+)
+
+var patternScore = map[Pattern]int{
+	Random:     100,
+	LowEntropy: 40,
+	ASCII:      30,
+	Sequential: 10,
+	Repeated:   10,
+	Constant:   0,
+	Empty:      0,
+}
+
+// SaltPattern classifies single salt
+func SaltPattern(salt []byte) Pattern {
+	n := len(salt)
+	switch {
+	case n == 0:
+		return Empty
+	case bytes.Count(salt, salt[:1]) == n:
+		return Constant
+	}
+	for p := 1; p <= n/2; p++ {
+		if bytes.Equal(salt[p:], salt[:n-p]) {
+			return Repeated
+		}
+	}
+	if n > 2 {
+		seq := true
+		for i := 2; i < n && seq; i++ {
+			seq = salt[i]-salt[i-1] == salt[1]-salt[0]
+		}
+		if seq {
+			return Sequential
+		}
+	}
+	printable := true
+	distinct := make(map[byte]bool)
+	for _, b := range salt {
+		printable = printable && b >= 0x20 && b < 0x7f
+		distinct[b] = true
+	}
+	switch {
+	case printable:
+		return ASCII
+	case len(distinct) < n/2:
+		return LowEntropy
+	}
+	return Random
+}
+
+// Fingerprint of hash generation
+type Fingerprint struct {
+	Kind    codvn.Kind
+	Iter    int
+	SaltLen int
+	Pattern Pattern
+}
+
+func (f Fingerprint) String() string {
+	return fmt.Sprintf("{x-is%s,%d} salt %d bytes %s", f.Kind, f.Iter, f.SaltLen, f.Pattern)
+}
+
+// Kernel parameters of hashes generated by the SAP kernel
+type Kernel struct {
+	Iter      map[codvn.Kind][]int // standard iterations by kind
+	SaltSizes map[codvn.Kind][]int // standard salt sizes in bytes by kind
+}
+
+// DefaultKernel parameters, login/password_hash_algorithm defaults
+var DefaultKernel = Kernel{
+	Iter: map[codvn.Kind][]int{
+		codvn.SHA1:   {1024},
+		codvn.SHA256: {10000},
+		codvn.SHA384: {7500},
+		codvn.SHA512: {15000},
+	},
+	SaltSizes: map[codvn.Kind][]int{
+		codvn.SHA1:   {12},
+		codvn.SHA256: {16},
+		codvn.SHA384: {12},
+		codvn.SHA512: {16},
+	},
+}
+
+func (k Kernel) standard(f Fingerprint) []string {
+	var reasons []string
+	known := false
+	for _, i := range k.Iter[f.Kind] {
+		known = known || i == f.Iter
+	}
+	if !known {
+		reasons = append(reasons, fmt.Sprintf("non-standard iterations %d for %s", f.Iter, f.Kind))
+	}
+	known = false
+	for _, s := range k.SaltSizes[f.Kind] {
+		known = known || s == f.SaltLen
+	}
+	if !known {
+		reasons = append(reasons, fmt.Sprintf("non-standard salt size %d for %s", f.SaltLen, f.Kind))
+	}
+	return reasons
+}
+
+// Result of user
+type Result struct {
+	Client string
+	User   string
+	Fingerprint
+	Score   int      // salt randomness 0 (none) to 100
+	Reasons []string // why hash is suspect
+}
+
+// Suspect reports whether hash likely wasn't created by the kernel
+func (r Result) Suspect() bool {
+	return len(r.Reasons) > 0
+}
+
+// Cluster of hashes sharing fingerprint
+type Cluster struct {
+	Fingerprint
+	Users int
+	Share float64 // of analysed hashes, in percent
+}
+
+// Report of analysis
+type Report struct {
+	Results  []Result
+	Clusters []Cluster // most frequent first
+}
+
+// Analyzer collects hashes of a dump
+type Analyzer struct {
+	Kernel   Kernel
+	MinScore int // salts scoring below are suspect
+	// NearDistance is the numeric distance below which salts of the same
+	// size count as sequential across users
+	NearDistance int64
+	records      []usr02.Record
+}
+
+// New analyzer with kernel defaults
+func New() *Analyzer {
+	return &Analyzer{Kernel: DefaultKernel, MinScore: 50, NearDistance: 1 << 16}
+}
+
+// Add record, records without PWDSALTEDHASH are skipped
+func (a *Analyzer) Add(r usr02.Record) {
+	if r.HasHash() {
+		a.records = append(a.records, r)
+	}
+}
+
+// Report analysis of added records
+func (a *Analyzer) Report() Report {
+	var rep Report
+	clusters := make(map[Fingerprint]int)
+	shared := make(map[string]int)
+	for _, r := range a.records {
+		shared[string(r.PwdSaltedHash.Salt)]++
+	}
+	for _, r := range a.records {
+		h := r.PwdSaltedHash
+		f := Fingerprint{Kind: h.Kind, Iter: h.Iter, SaltLen: len(h.Salt), Pattern: SaltPattern(h.Salt)}
+		res := Result{Client: r.Client, User: r.User, Fingerprint: f, Score: patternScore[f.Pattern]}
+		if n := shared[string(h.Salt)]; n > 1 {
+			res.Score = 0
+			res.Reasons = append(res.Reasons, fmt.Sprintf("salt shared by %d users", n))
+		}
+		if res.Score < a.MinScore && f.Pattern != Random {
+			res.Reasons = append(res.Reasons, fmt.Sprintf("%s salt", f.Pattern))
+		}
+		res.Reasons = append(res.Reasons, a.Kernel.standard(f)...)
+		rep.Results = append(rep.Results, res)
+		clusters[f]++
+	}
+	a.near(rep.Results)
+	for f, n := range clusters {
+		rep.Clusters = append(rep.Clusters, Cluster{
+			Fingerprint: f,
+			Users:       n,
+			Share:       100 * float64(n) / float64(len(a.records)),
+		})
+	}
+	sort.Slice(rep.Clusters, func(i, j int) bool {
+		if rep.Clusters[i].Users != rep.Clusters[j].Users {
+			return rep.Clusters[i].Users > rep.Clusters[j].Users
+		}
+		return rep.Clusters[i].String() < rep.Clusters[j].String()
+	})
+	return rep
+}
+
+// near flags salts numerically close to salt of another user, as produced
+// by counters or time based generators
+func (a *Analyzer) near(results []Result) {
+	type salt struct {
+		n *big.Int
+		i int
+	}
+	bySize := make(map[int][]salt)
+	for i, r := range a.records {
+		s := r.PwdSaltedHash.Salt
+		bySize[len(s)] = append(bySize[len(s)], salt{n: new(big.Int).SetBytes(s), i: i})
+	}
+	limit := big.NewInt(a.NearDistance)
+	d := new(big.Int)
+	for _, salts := range bySize {
+		sort.Slice(salts, func(i, j int) bool { return salts[i].n.Cmp(salts[j].n) < 0 })
+		for k := 1; k < len(salts); k++ {
+			d.Sub(salts[k].n, salts[k-1].n)
+			if d.Sign() == 0 || d.Cmp(limit) >= 0 {
+				continue
+			}
+			for _, s := range []salt{salts[k-1], salts[k]} {
+				r := &results[s.i]
+				if r.Score > 10 {
+					r.Score = 10
+				}
+				r.Reasons = appendOnce(r.Reasons, "salt sequential to salt of other user")
+			}
+		}
+	}
+}
+
+func appendOnce(l []string, s string) []string {
+	for _, v := range l {
+		if v == s {
+			return l
+		}
+	}
+	return append(l, s)
+}
@@ -0,0 +1,95 @@
+package forensics
+
+import (
+	"testing"
+
+	"github.com/dim13/codvn"
+	"github.com/dim13/codvn/usr02"
+)
+
+func TestSaltPattern(t *testing.T) {
+	testCases := []struct {
+		salt string
+		want Pattern
+	}{
+		{salt: "", want: Empty},
+		{salt: "\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00", want: Constant},
+		{salt: "saltsaltsalt", want: Repeated},
+		{salt: "\x00\x01\x02\x03\x04\x05\x06\x07\x08\x09\x0a\x0b", want: Sequential},
+		{salt: "Xk9#pQ2!mZr7", want: ASCII},
+		{salt: "\x01\x02\x01\x80\x02\x01\x80\x01\x02\x80\x01\x02", want: LowEntropy},
+		{salt: "\x8f\x12\xe4\x00\x5b\xc9\x77\x3a\xd0\x61\xfe\x2c", want: Random},
+	}
+	for _, tc := range testCases {
+		t.Run(string(tc.want), func(t *testing.T) {
+			if got := SaltPattern([]byte(tc.salt)); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func record(user string, kind codvn.Kind, iter int, salt string) usr02.Record {
+	return usr02.Record{
+		Client: "000",
+		User:   user,
+		Type:   usr02.Dialog,
+		Codvn:  usr02.CodvnH,
+		PwdSaltedHash: codvn.CodvN{
+			Kind: kind,
+			Iter: iter,
+			Hash: make([]byte, 64),
+			Salt: []byte(salt),
+		},
+	}
+}
+
+func TestReport(t *testing.T) {
+	a := New()
+	for _, r := range []usr02.Record{
+		record("KERNEL1", codvn.SHA512, 15000, "\x8f\x12\xe4\x00\x5b\xc9\x77\x3a\xd0\x61\xfe\x2c\x11\x93\x4e\xa7"),
+		record("KERNEL2", codvn.SHA512, 15000, "\x3c\xa1\x07\xe9\x52\x8d\x14\xf6\x6b\x20\xc7\x99\x0e\x45\xbd\x73"),
+		record("ODDITER", codvn.SHA512, 5000, "\xd2\x4f\x81\x1b\xe6\x38\x90\x5c\xa9\x07\x6e\xf3\x22\xb5\x4d\x10"),
+		record("ODDSALT", codvn.SHA512, 15000, "\x6d\x93\x0a\xf1\x2e\xb8\x47\xc5"),
+		record("SHORTSALT", codvn.SHA512, 15000, "\xa7\x3e\x19\xc4\x5d\x82\xf0\x6b\x2e\x91\xd3\x48"),
+		record("SHA1", codvn.SHA1, 1024, "\x4b\xe2\x07\x9d\xc1\x68\x3f\xa5\x12\xdb\x76\x0e"),
+		record("LONGSALT", codvn.SHA1, 1024, "\x8e\x25\xd1\x6a\x03\xbf\x94\x47\xe8\x1c\x5b\xa2\x39\xf6\x70\xcd"),
+		record("ASCII", codvn.SHA512, 15000, "Xk9#pQ2!mZr7Lw4@"),
+		record("SHARED1", codvn.SHA512, 15000, "\x19\xe7\x52\x0c\xa4\x3b\xd8\x61\xf0\x8e\x27\xc3\x5a\x96\x04\xbf"),
+		record("SHARED2", codvn.SHA512, 15000, "\x19\xe7\x52\x0c\xa4\x3b\xd8\x61\xf0\x8e\x27\xc3\x5a\x96\x04\xbf"),
+		record("COUNTER1", codvn.SHA512, 15000, "\x55\x01\xa3\x7e\x9c\x20\xfb\x48\x36\xd7\x6a\x0e\xc2\x11\x00\x01"),
+		record("COUNTER2", codvn.SHA512, 15000, "\x55\x01\xa3\x7e\x9c\x20\xfb\x48\x36\xd7\x6a\x0e\xc2\x11\x00\x02"),
+		{Client: "000", User: "NOHASH"},
+	} {
+		a.Add(r)
+	}
+	rep := a.Report()
+	want := map[string]bool{
+		"KERNEL1":   false,
+		"KERNEL2":   false,
+		"ODDITER":   true,
+		"ODDSALT":   true,
+		"SHORTSALT": true,
+		"SHA1":      false,
+		"LONGSALT":  true,
+		"ASCII":     true,
+		"SHARED1":   true,
+		"SHARED2":   true,
+		"COUNTER1":  true,
+		"COUNTER2":  true,
+	}
+	if len(rep.Results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(rep.Results), len(want))
+	}
+	for _, r := range rep.Results {
+		t.Run(r.User, func(t *testing.T) {
+			if got := r.Suspect(); got != want[r.User] {
+				t.Errorf("got %v, want %v (%q)", got, want[r.User], r.Reasons)
+			}
+		})
+	}
+	top := rep.Clusters[0]
+	if top.Users != 6 || top.Pattern != Random || top.Iter != 15000 {
+		t.Errorf("got %v, want 6 random salts at 15000 iterations", top)
+	}
+}
@@ -1,3 +1,5 @@
 module github.com/dim13/codvn
 
 go 1.12
+
+require github.com/mattn/go-sqlite3 v1.14.33
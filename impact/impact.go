@@ -0,0 +1,192 @@
+// Package impact simulates consequences of changing login profile
+// parameters on users of a USR02 dump
+//
+// For each user it reports, under the proposed parameters:
+//   - rehash: kernel writes new PWDSALTEDHASH on next password logon
+//   - drop-legacy: BCODE and PASSCODE are no longer kept
+//   - forced-change: password is known to violate rules and
+//     login/password_compliance_to_current_policy forces a change
+//   - break: password logon fails, critical for interface users
+//
+// Effects not caused by the current parameters are marked new.
+package impact
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/dim13/codvn/audit"
+	"github.com/dim13/codvn/policy"
+	"github.com/dim13/codvn/usr02"
+)
+
+// Impact of parameter change on user
+type Impact string
+
+// Impacts
+const (
+	Rehash       Impact = "rehash"
+	DropLegacy   Impact = "drop-legacy"
+	ForcedChange Impact = "forced-change"
+	Break        Impact = "break"
+)
+
+// Evaluated is the lowest login/password_downwards_compatibility at which
+// the kernel checks BCODE and PASSCODE on logon
+const Evaluated = 2
+
+// Known returns password of user where recovered by audit
+type Known func(r usr02.Record) ([]byte, bool)
+
+// Recovered returns passwords found by the audit default-password check
+// and, if listed, passwords recovered elsewhere, e.g. by a cracking run,
+// keyed by client and user as "100/JDOE". Only passwords matching the hash
+// count, no hashes are cracked.
+func Recovered(list map[string]string) Known {
+	return func(r usr02.Record) ([]byte, bool) {
+		if !r.HasHash() {
+			return nil, false
+		}
+		words := audit.DefaultPasswords[r.User]
+		if w, ok := list[r.Client+"/"+r.User]; ok {
+			words = append([]string{w}, words...)
+		}
+		for _, w := range words {
+			if r.PwdSaltedHash.Verify([]byte(w)) == nil {
+				return []byte(w), true
+			}
+		}
+		return nil, false
+	}
+}
+
+// Effect on user
+type Effect struct {
+	Impact Impact
+	Reason string
+	New    bool // not caused by current parameters
+}
+
+// Result of user
+type Result struct {
+	Client    string
+	User      string
+	Type      string
+	Interface bool // system, communication or service user
+	Effects   []Effect
+}
+
+// Report of simulation
+type Report struct {
+	Users   int
+	Counts  map[Impact]int // users affected under proposed parameters
+	New     map[Impact]int // of those, users not affected under current
+	Results []Result       // affected users
+}
+
+// Simulator of parameter change
+type Simulator struct {
+	Current  policy.Policy
+	Proposed policy.Policy
+	Known    Known // optional
+	report   Report
+}
+
+// New simulator
+func New(current, proposed policy.Policy) *Simulator {
+	return &Simulator{
+		Current:  current,
+		Proposed: proposed,
+		report: Report{
+			Counts: make(map[Impact]int),
+			New:    make(map[Impact]int),
+		},
+	}
+}
+
+func isInterface(typ string) bool {
+	return typ == usr02.System || typ == usr02.Communication || typ == usr02.Service
+}
+
+func hasLegacy(r usr02.Record) bool {
+	return !r.Bcode.IsZero() || !r.Passcode.IsZero()
+}
+
+// effects of parameters on record, known password may be nil
+func effects(p policy.Policy, r usr02.Record, pass []byte) map[Impact]string {
+	m := make(map[Impact]string)
+	if r.Type == usr02.Reference || (!r.HasHash() && !hasLegacy(r)) {
+		return m
+	}
+	h := r.PwdSaltedHash
+	switch {
+	case !r.HasHash() && p.Compat < Evaluated:
+		m[Break] = fmt.Sprintf("only legacy hashes, not evaluated at compatibility %d", p.Compat)
+	case !r.HasHash():
+		m[Rehash] = fmt.Sprintf("only legacy hashes, new {x-is%s,%d} with %d byte salt", p.Kind, p.Iter, p.SaltSize)
+	case h.Kind != p.Kind || h.Iter != p.Iter || len(h.Salt) != p.SaltSize:
+		m[Rehash] = fmt.Sprintf("{x-is%s,%d} with %d byte salt to {x-is%s,%d} with %d byte salt",
+			h.Kind, h.Iter, len(h.Salt), p.Kind, p.Iter, p.SaltSize)
+	}
+	if hasLegacy(r) && p.Compat == 0 {
+		m[DropLegacy] = "legacy hashes not kept at compatibility 0"
+	}
+	if p.Compliance && r.Type == usr02.Dialog && pass != nil {
+		rules := p
+		rules.MinScore = 0
+		if err := rules.Check(pass); err != nil {
+			m[ForcedChange] = err.Error()
+		}
+	}
+	return m
+}
+
+// Add user record to simulation
+func (s *Simulator) Add(r usr02.Record) {
+	s.report.Users++
+	var pass []byte
+	if s.Known != nil {
+		pass, _ = s.Known(r)
+	}
+	proposed := effects(s.Proposed, r, pass)
+	if len(proposed) == 0 {
+		return
+	}
+	current := effects(s.Current, r, pass)
+	res := Result{
+		Client:    r.Client,
+		User:      r.User,
+		Type:      r.Type,
+		Interface: isInterface(r.Type),
+	}
+	for _, i := range []Impact{Rehash, DropLegacy, ForcedChange, Break} {
+		reason, ok := proposed[i]
+		if !ok {
+			continue
+		}
+		_, was := current[i]
+		res.Effects = append(res.Effects, Effect{Impact: i, Reason: reason, New: !was})
+		s.report.Counts[i]++
+		if !was {
+			s.report.New[i]++
+		}
+	}
+	s.report.Results = append(s.report.Results, res)
+}
+
+// Report of simulation, broken interface users first
+func (s *Simulator) Report() Report {
+	r := s.report
+	rank := func(res Result) int {
+		for _, e := range res.Effects {
+			if e.Impact == Break && res.Interface {
+				return 0
+			}
+		}
+		return 1
+	}
+	sort.SliceStable(r.Results, func(i, j int) bool {
+		return rank(r.Results[i]) < rank(r.Results[j])
+	})
+	return r
+}
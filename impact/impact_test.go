@@ -0,0 +1,111 @@
+package impact
+
+import (
+	"testing"
+
+	"github.com/dim13/codvn"
+	"github.com/dim13/codvn/audit"
+	"github.com/dim13/codvn/policy"
+	"github.com/dim13/codvn/usr02"
+)
+
+func TestSimulator(t *testing.T) {
+	current := policy.Kernel
+	current.Kind, current.Iter, current.SaltSize = codvn.SHA1, 1024, 12
+	current.Compat = 3
+	proposed := policy.Kernel
+	proposed.Iter = 10
+	proposed.Compat = 0
+	proposed.Compliance = true
+	proposed.MinLength = 10
+
+	welcome1, err := codvn.New(codvn.SHA512, []byte("Welcome1"), make([]byte, 16), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ddic, err := codvn.New(codvn.SHA512, []byte(audit.DefaultPasswords["DDIC"][0]), make([]byte, 16), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// hashes of unknown passwords
+	sha1 := codvn.CodvN{Kind: codvn.SHA1, Iter: 1024, Salt: make([]byte, 12), Hash: make([]byte, 20)}
+	sha512 := codvn.CodvN{Kind: codvn.SHA512, Iter: 10, Salt: make([]byte, 16), Hash: make([]byte, 64)}
+
+	s := New(current, proposed)
+	s.Known = Recovered(map[string]string{
+		"/WEAK":     "Welcome1",
+		"/RFC_WEAK": "Welcome1",
+		"/CURRENT":  "Welcome1", // stale, doesn't match hash
+	})
+	testCases := []struct {
+		rec  usr02.Record
+		want map[Impact]bool // impact and whether new
+	}{
+		{
+			rec:  usr02.Record{User: "OLDHASH", Type: usr02.Dialog, PwdSaltedHash: sha1},
+			want: map[Impact]bool{Rehash: true},
+		},
+		{
+			rec:  usr02.Record{User: "CURRENT", Type: usr02.Dialog, PwdSaltedHash: sha512},
+			want: map[Impact]bool{},
+		},
+		{
+			rec:  usr02.Record{User: "WEAK", Type: usr02.Dialog, PwdSaltedHash: welcome1},
+			want: map[Impact]bool{ForcedChange: true},
+		},
+		{
+			rec:  usr02.Record{User: "DDIC", Type: usr02.Dialog, PwdSaltedHash: ddic},
+			want: map[Impact]bool{ForcedChange: true},
+		},
+		{
+			rec:  usr02.Record{User: "RFC_WEAK", Type: usr02.System, PwdSaltedHash: welcome1},
+			want: map[Impact]bool{},
+		},
+		{
+			rec: usr02.Record{User: "MIXED", Type: usr02.Dialog, PwdSaltedHash: sha1,
+				Bcode: make(usr02.Bcode, usr02.BcodeSize), Passcode: make(usr02.Passcode, usr02.PasscodeSize)},
+			want: map[Impact]bool{Rehash: true, DropLegacy: true},
+		},
+		{
+			rec:  usr02.Record{User: "RFC_OLD", Type: usr02.Communication, Bcode: make(usr02.Bcode, usr02.BcodeSize)},
+			want: map[Impact]bool{Break: true, DropLegacy: true},
+		},
+		{
+			rec:  usr02.Record{User: "TEMPLATE", Type: usr02.Reference, Bcode: make(usr02.Bcode, usr02.BcodeSize)},
+			want: map[Impact]bool{},
+		},
+	}
+	for _, tc := range testCases {
+		s.Add(tc.rec)
+	}
+	rep := s.Report()
+	if rep.Users != len(testCases) {
+		t.Errorf("got %d users, want %d", rep.Users, len(testCases))
+	}
+	if len(rep.Results) == 0 || rep.Results[0].User != "RFC_OLD" || !rep.Results[0].Interface {
+		t.Errorf("got %v, want broken interface user first", rep.Results)
+	}
+	got := make(map[string]map[Impact]bool)
+	for _, r := range rep.Results {
+		got[r.User] = make(map[Impact]bool)
+		for _, e := range r.Effects {
+			got[r.User][e.Impact] = e.New
+		}
+	}
+	for _, tc := range testCases {
+		t.Run(tc.rec.User, func(t *testing.T) {
+			g := got[tc.rec.User]
+			if len(g) != len(tc.want) {
+				t.Fatalf("got %v, want %v", g, tc.want)
+			}
+			for i, n := range tc.want {
+				if isNew, ok := g[i]; !ok || isNew != n {
+					t.Errorf("got %v, want %v", g, tc.want)
+				}
+			}
+		})
+	}
+	if rep.Counts[Rehash] != 2 || rep.New[Break] != 1 {
+		t.Errorf("got %v %v, want 2 rehashes and 1 new break", rep.Counts, rep.New)
+	}
+}
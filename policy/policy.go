@@ -0,0 +1,235 @@
+// Package policy applies SAP password rules and hashing parameters
+//
+// Rules follow the login/min_password_* profile parameters, hashing follows
+// login/password_hash_algorithm.
+package policy
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"math/big"
+	"strings"
+	"unicode"
+
+	"github.com/dim13/codvn"
+	"github.com/dim13/codvn/strength"
+	"github.com/dim13/codvn/usr02"
+)
+
+// Errors
+var (
+	ErrTooShort  = errors.New("password too short")
+	ErrTooLong   = errors.New("password too long")
+	ErrDigits    = errors.New("not enough digits")
+	ErrLetters   = errors.New("not enough letters")
+	ErrLowercase = errors.New("not enough lowercase letters")
+	ErrUppercase = errors.New("not enough uppercase letters")
+	ErrSpecials  = errors.New("not enough special characters")
+	ErrFirstChar = errors.New("password starts with '!', '?' or space")
+	ErrRepeated  = errors.New("password starts with three identical characters")
+	ErrWeak      = errors.New("password too weak")
+	ErrLegacy    = errors.New("BCODE (code version B) not implemented")
+)
+
+// MaxLength of SAP password
+const MaxLength = 40
+
+// Policy for passwords
+type Policy struct {
+	Kind     codvn.Kind // hash algorithm
+	Iter     int        // iterations
+	SaltSize int        // salt size in bytes
+
+	MinLength    int // login/min_password_lng
+	MinDigits    int // login/min_password_digits
+	MinLetters   int // login/min_password_letters
+	MinLowercase int // login/min_password_lowercase
+	MinUppercase int // login/min_password_uppercase
+	MinSpecials  int // login/min_password_specials
+
+	MinScore int // minimal strength score, 0 disables estimation
+
+	Compliance bool // login/password_compliance_to_current_policy
+
+	// login/password_downwards_compatibility. Records above 0 need BCODE
+	// (code version B), which is not implemented yet, so Record fails.
+	Compat int
+}
+
+// Default policy, kernel defaults for hashing, strict rules and strength
+var Default = Policy{
+	Kind:         codvn.SHA512,
+	Iter:         15000,
+	SaltSize:     16,
+	MinLength:    12,
+	MinDigits:    1,
+	MinLetters:   1,
+	MinLowercase: 1,
+	MinUppercase: 1,
+	MinSpecials:  1,
+	MinScore:     3,
+}
+
+type classes struct {
+	digits, letters, lower, upper, specials int
+}
+
+func count(pass []rune) classes {
+	var c classes
+	for _, r := range pass {
+		switch {
+		case unicode.IsDigit(r):
+			c.digits++
+		case unicode.IsLetter(r):
+			c.letters++
+			if unicode.IsLower(r) {
+				c.lower++
+			}
+			if unicode.IsUpper(r) {
+				c.upper++
+			}
+		default:
+			c.specials++
+		}
+	}
+	return c
+}
+
+// Verifier rejecting hashes weaker than policy
+func (p Policy) Verifier() codvn.Verifier {
+	return codvn.Verifier{Kinds: []codvn.Kind{p.Kind}, MinIter: p.Iter, MinSalt: p.SaltSize}
+}
+
+// Check password against rules, user inputs such as user name are
+// considered by strength estimation
+func (p Policy) Check(pass []byte, user ...string) error {
+	r := []rune(string(pass))
+	switch {
+	case len(r) < p.MinLength || len(r) == 0:
+		return ErrTooShort
+	case len(r) > MaxLength:
+		return ErrTooLong
+	case r[0] == '!' || r[0] == '?' || r[0] == ' ':
+		return ErrFirstChar
+	case len(r) >= 3 && r[0] == r[1] && r[1] == r[2]:
+		return ErrRepeated
+	}
+	c := count(r)
+	switch {
+	case c.digits < p.MinDigits:
+		return ErrDigits
+	case c.letters < p.MinLetters:
+		return ErrLetters
+	case c.lower < p.MinLowercase:
+		return ErrLowercase
+	case c.upper < p.MinUppercase:
+		return ErrUppercase
+	case c.specials < p.MinSpecials:
+		return ErrSpecials
+	}
+	if p.MinScore > 0 && strength.Estimate(string(pass), user...).Score < p.MinScore {
+		return ErrWeak
+	}
+	return nil
+}
+
+// Explain weaknesses of password
+func (p Policy) Explain(pass []byte, user ...string) []string {
+	var w []string
+	switch err := p.Check(pass, user...); err {
+	case nil, ErrWeak:
+	case ErrTooLong:
+		return []string{err.Error()}
+	default:
+		w = append(w, err.Error())
+	}
+	return append(w, strength.Estimate(string(pass), user...).Warnings()...)
+}
+
+// New checks password and hashes it with random salt
+func (p Policy) New(pass []byte) (codvn.CodvN, error) {
+	if err := p.Check(pass); err != nil {
+		return codvn.CodvN{}, err
+	}
+	return p.hash(pass)
+}
+
+// hash password with random salt, unchecked
+func (p Policy) hash(pass []byte) (codvn.CodvN, error) {
+	salt := make([]byte, p.SaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return codvn.CodvN{}, err
+	}
+	return codvn.New(p.Kind, pass, salt, p.Iter)
+}
+
+// Record checks password and returns USR02 password columns of user,
+// CODVN H only. Downwards compatibility needs BCODE and fails with
+// ErrLegacy.
+func (p Policy) Record(client, user string, pass []byte) (usr02.Record, error) {
+	if p.Compat > 0 {
+		return usr02.Record{}, ErrLegacy
+	}
+	if err := p.Check(pass, user); err != nil {
+		return usr02.Record{}, err
+	}
+	h, err := p.hash(pass)
+	if err != nil {
+		return usr02.Record{}, err
+	}
+	r := usr02.Record{
+		Client:        client,
+		User:          strings.ToUpper(user),
+		Codvn:         usr02.CodvnH,
+		PwdSaltedHash: h,
+	}
+	return r, nil
+}
+
+// Character sets of generated passwords
+const (
+	Digits    = "0123456789"
+	Lowercase = "abcdefghijklmnopqrstuvwxyz"
+	Uppercase = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	Specials  = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+)
+
+// Generate random password of given length satisfying rules
+func (p Policy) Generate(length int) ([]byte, error) {
+	if length < p.MinLength {
+		length = p.MinLength
+	}
+	if length > MaxLength {
+		return nil, ErrTooLong
+	}
+	letters := p.MinLowercase + p.MinUppercase
+	if letters < p.MinLetters {
+		letters = p.MinLetters
+	}
+	if p.MinDigits+letters+p.MinSpecials > length {
+		return nil, ErrTooShort
+	}
+	const all = Digits + Lowercase + Uppercase + Specials
+	for {
+		pass := make([]byte, length)
+		for i := range pass {
+			c, err := pick(all)
+			if err != nil {
+				return nil, err
+			}
+			pass[i] = c
+		}
+		if p.Check(pass) == nil {
+			return pass, nil
+		}
+	}
+}
+
+func pick(set string) (byte, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(set))))
+	if err != nil {
+		return 0, err
+	}
+	return set[n.Int64()], nil
+}
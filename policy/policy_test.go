@@ -0,0 +1,111 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dim13/codvn/usr02"
+)
+
+func TestCheck(t *testing.T) {
+	testCases := []struct {
+		pass string
+		user string
+		err  error
+	}{
+		{pass: `Pindakaas!123`},
+		{pass: ``, err: ErrTooShort},
+		{pass: `Pk!1`, err: ErrTooShort},
+		{pass: `Pindakaas!123Pindakaas!123Pindakaas!123xx`, err: ErrTooLong},
+		{pass: `!Pindakaas123`, err: ErrFirstChar},
+		{pass: `PPPindakaas!1`, err: ErrRepeated},
+		{pass: `Pindakaas!abc`, err: ErrDigits},
+		{pass: `1234567890!12`, err: ErrLetters},
+		{pass: `PINDAKAAS!123`, err: ErrLowercase},
+		{pass: `pindakaas!123`, err: ErrUppercase},
+		{pass: `Pindakaas0123`, err: ErrSpecials},
+		{pass: `Password123!`, err: ErrWeak},
+		{pass: `Welcome2024!`, err: ErrWeak},
+		{pass: `Firefighter1!`, user: "FIREFIGHTER", err: ErrWeak},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.pass, func(t *testing.T) {
+			if err := Default.Check([]byte(tc.pass), tc.user); err != tc.err {
+				t.Errorf("got %v, want %v", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	for _, length := range []int{0, 12, 20, MaxLength} {
+		pass, err := Default.Generate(length)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Default.Check(pass); err != nil {
+			t.Errorf("%q: %v", pass, err)
+		}
+	}
+	if _, err := Default.Generate(MaxLength + 1); err != ErrTooLong {
+		t.Errorf("got %v, want %v", err, ErrTooLong)
+	}
+	p := Policy{MinDigits: 3, MinLetters: 3}
+	if _, err := p.Generate(5); err != ErrTooShort {
+		t.Errorf("got %v, want %v", err, ErrTooShort)
+	}
+}
+
+func TestNew(t *testing.T) {
+	p := Default
+	p.Iter = 10
+	c, err := p.New([]byte(`Pindakaas!123`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Salt) != p.SaltSize || c.Kind != p.Kind || c.Iter != p.Iter {
+		t.Errorf("got %v", c)
+	}
+	if err := c.Verify([]byte(`Pindakaas!123`)); err != nil {
+		t.Error(err)
+	}
+	if err := p.Verifier().Verify(c, []byte(`Pindakaas!123`)); err != nil {
+		t.Error(err)
+	}
+	if _, err := p.New([]byte(`short`)); err != ErrTooShort {
+		t.Errorf("got %v, want %v", err, ErrTooShort)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	if w := Default.Explain([]byte(`Password1`)); len(w) < 2 || w[0] != ErrTooShort.Error() {
+		t.Errorf("got %q", w)
+	}
+	if w := Default.Explain([]byte(`hX4#qv9!Lm2pZ8`)); len(w) != 0 {
+		t.Errorf("got %q", w)
+	}
+	if w := Default.Explain([]byte(strings.Repeat(`hX4#qv9!Lm2pZ8`, 300))); len(w) != 1 || w[0] != ErrTooLong.Error() {
+		t.Errorf("got %q, want %q", w, ErrTooLong)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	p := Default
+	p.Iter = 10
+	pass := []byte(`hX4#qv9!Lm2pZ8`)
+	r, err := p.Record("001", "rfc_user", pass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.User != "RFC_USER" || r.Codvn != usr02.CodvnH || !r.Bcode.IsZero() {
+		t.Errorf("got %+v", r)
+	}
+	if err := r.PwdSaltedHash.Verify(pass); err != nil {
+		t.Error(err)
+	}
+
+	p.Compat = 1 // needs BCODE
+	if _, err := p.Record("001", "RFC_USER", pass); err != ErrLegacy {
+		t.Errorf("got %v, want %v", err, ErrLegacy)
+	}
+}
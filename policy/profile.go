@@ -0,0 +1,127 @@
+package policy
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/dim13/codvn"
+)
+
+// ErrProfile is returned on malformed profile parameter
+var ErrProfile = errors.New("malformed profile parameter")
+
+// Kernel defaults of profile parameters
+var Kernel = Policy{
+	Kind:      codvn.SHA512,
+	Iter:      15000,
+	SaltSize:  16,
+	MinLength: 6,
+	Compat:    1,
+}
+
+var algorithms = map[string]codvn.Kind{
+	"iSSHA-1":   codvn.SHA1,
+	"iSSHA-256": codvn.SHA256,
+	"iSSHA-384": codvn.SHA384,
+	"iSSHA-512": codvn.SHA512,
+}
+
+// ParseProfile reads login parameters of instance profile, such as
+//
+//	login/password_hash_algorithm = encoding=RFC2307, algorithm=iSSHA-512, iterations=15000, saltsize=128
+//	login/min_password_lng = 12
+//
+// over base. Other parameters and comments are ignored.
+func ParseProfile(r io.Reader, base Policy) (Policy, error) {
+	p := base
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		name, value := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		if err := p.set(name, value); err != nil {
+			return p, fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return p, s.Err()
+}
+
+func (p *Policy) set(name, value string) error {
+	ints := map[string]*int{
+		"login/min_password_lng":                 &p.MinLength,
+		"login/min_password_digits":              &p.MinDigits,
+		"login/min_password_letters":             &p.MinLetters,
+		"login/min_password_lowercase":           &p.MinLowercase,
+		"login/min_password_uppercase":           &p.MinUppercase,
+		"login/min_password_specials":            &p.MinSpecials,
+		"login/password_downwards_compatibility": &p.Compat,
+	}
+	if v, ok := ints[name]; ok {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return ErrProfile
+		}
+		*v = n
+		return nil
+	}
+	switch name {
+	case "login/password_compliance_to_current_policy":
+		switch value {
+		case "0":
+			p.Compliance = false
+		case "1":
+			p.Compliance = true
+		default:
+			return ErrProfile
+		}
+	case "login/password_hash_algorithm":
+		return p.setAlgorithm(value)
+	}
+	return nil
+}
+
+func (p *Policy) setAlgorithm(value string) error {
+	for _, f := range strings.Split(value, ",") {
+		kv := strings.SplitN(strings.TrimSpace(f), "=", 2)
+		if len(kv) != 2 {
+			return ErrProfile
+		}
+		switch kv[0] {
+		case "encoding":
+			if kv[1] != "RFC2307" {
+				return ErrProfile
+			}
+		case "algorithm":
+			k, ok := algorithms[kv[1]]
+			if !ok {
+				return ErrProfile
+			}
+			p.Kind = k
+		case "iterations":
+			n, err := strconv.Atoi(kv[1])
+			if err != nil || n < 1 {
+				return ErrProfile
+			}
+			p.Iter = n
+		case "saltsize":
+			n, err := strconv.Atoi(kv[1])
+			if err != nil || n < 8 || n%8 != 0 {
+				return ErrProfile
+			}
+			p.SaltSize = n / 8
+		default:
+			return ErrProfile
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dim13/codvn"
+)
+
+func TestParseProfile(t *testing.T) {
+	testCases := []struct {
+		name    string
+		profile string
+		want    Policy
+		wantErr bool
+	}{
+		{
+			name:    "defaults",
+			profile: "# instance profile\nrdisp/wp_no_dia = 10\n",
+			want:    Kernel,
+		},
+		{
+			name: "hardened",
+			profile: `login/password_hash_algorithm = encoding=RFC2307, algorithm=iSSHA-256, iterations=20000, saltsize=96
+login/password_downwards_compatibility = 0
+login/password_compliance_to_current_policy = 1
+login/min_password_lng = 12
+login/min_password_digits = 1
+login/min_password_letters = 2
+login/min_password_lowercase = 1
+login/min_password_uppercase = 1
+login/min_password_specials = 1
+`,
+			want: Policy{
+				Kind:         codvn.SHA256,
+				Iter:         20000,
+				SaltSize:     12,
+				MinLength:    12,
+				MinDigits:    1,
+				MinLetters:   2,
+				MinLowercase: 1,
+				MinUppercase: 1,
+				MinSpecials:  1,
+				Compliance:   true,
+			},
+		},
+		{name: "bad algorithm", profile: "login/password_hash_algorithm = encoding=RFC2307, algorithm=MD5", wantErr: true},
+		{name: "bad saltsize", profile: "login/password_hash_algorithm = saltsize=100", wantErr: true},
+		{name: "bad number", profile: "login/min_password_lng = twelve", wantErr: true},
+		{name: "bad compliance", profile: "login/password_compliance_to_current_policy = yes", wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseProfile(strings.NewReader(tc.profile), Kernel)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got %v, want error %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if got.Kind != tc.want.Kind || got.Iter != tc.want.Iter || got.SaltSize != tc.want.SaltSize ||
+				got.MinLength != tc.want.MinLength || got.MinDigits != tc.want.MinDigits ||
+				got.MinLetters != tc.want.MinLetters || got.MinLowercase != tc.want.MinLowercase ||
+				got.MinUppercase != tc.want.MinUppercase || got.MinSpecials != tc.want.MinSpecials ||
+				got.Compat != tc.want.Compat || got.Compliance != tc.want.Compliance {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
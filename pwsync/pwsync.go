@@ -0,0 +1,310 @@
+// Package pwsync receives password changes from a central directory and
+// queues matching USR02 hash columns for each target SAP system
+//
+// The caller posts the cleartext once, over mutually authenticated TLS, as
+// JSON:
+//
+//	{"user": "JDOE", "password": "..."}
+//
+// The password is checked against the policy of every target first. If any
+// target rejects it, nothing is queued and the caller gets 422 with reasons,
+// so the directory can refuse the change. Otherwise one change file per
+// target is written to the queue, all or none. The cleartext is never
+// written to disk or logs.
+//
+// Targets with login/password_downwards_compatibility above 0 reject every
+// change until BCODE is implemented, see policy.Policy.Record.
+package pwsync
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dim13/codvn/policy"
+	"github.com/dim13/codvn/usr02"
+)
+
+// Errors
+var (
+	ErrTarget = errors.New("target requires system and client")
+	ErrCaller = errors.New("caller not authorized")
+)
+
+// MaxBody limits size of request
+const MaxBody = 4096
+
+// Target system
+type Target struct {
+	System string        `json:"system"`
+	Client string        `json:"client"`
+	Policy policy.Policy `json:"policy"`
+}
+
+// UnmarshalJSON decodes target, unset policy fields keep policy.Default
+func (t *Target) UnmarshalJSON(b []byte) error {
+	type target Target
+	v := target{Policy: policy.Default}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if v.System == "" || v.Client == "" {
+		return ErrTarget
+	}
+	*t = Target(v)
+	return nil
+}
+
+// Config of receiver
+type Config struct {
+	Targets []Target `json:"targets"`
+	Callers []string `json:"callers"` // allowed client certificate common names
+}
+
+// LoadConfig reads JSON configuration
+func LoadConfig(path string) (Config, error) {
+	var c Config
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(data, &c)
+	return c, err
+}
+
+// TLSConfig for server requiring client certificates issued by clients
+func TLSConfig(cert tls.Certificate, clients *x509.CertPool) *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clients,
+		MinVersion:   tls.VersionTLS12,
+	}
+}
+
+// Queue of change files, one directory per system
+type Queue struct {
+	Dir string
+}
+
+// Change file of system
+type Change struct {
+	System  string
+	Records []usr02.Record
+}
+
+// Put writes change files and returns their paths. All files are staged
+// first and moved into place only if every one was written, so a failed
+// Put may be retried without duplicates.
+func (q Queue) Put(changes ...Change) ([]string, error) {
+	var temps []string
+	for _, c := range changes {
+		temp, err := q.stage(c)
+		if err != nil {
+			remove(temps)
+			return nil, err
+		}
+		temps = append(temps, temp)
+	}
+	paths := make([]string, 0, len(temps))
+	for i, temp := range temps {
+		name := fmt.Sprintf("%s-%s.tsv", time.Now().UTC().Format("20060102T150405.000000000"), filepath.Base(temp)[len(".change-"):])
+		path := filepath.Join(filepath.Dir(temp), name)
+		if err := os.Rename(temp, path); err != nil {
+			remove(append(paths, temps[i:]...))
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+func remove(paths []string) {
+	for _, p := range paths {
+		os.Remove(p)
+	}
+}
+
+// stage writes change to temporary file in directory of system
+func (q Queue) stage(c Change) (string, error) {
+	dir := filepath.Join(q.Dir, c.System)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	f, err := ioutil.TempFile(dir, ".change-*")
+	if err != nil {
+		return "", err
+	}
+	w := usr02.NewChangeWriter(f)
+	for _, r := range c.Records {
+		if err := w.Write(r); err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			return "", err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// Request of password change
+type Request struct {
+	User     string `json:"user"`
+	Password string `json:"password"`
+}
+
+// Result per target
+type Result struct {
+	System  string   `json:"system"`
+	Client  string   `json:"client"`
+	Queued  bool     `json:"queued"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// Handler receives password changes
+type Handler struct {
+	Targets  []Target
+	Callers  []string // allowed client certificate common names, any if empty
+	Queue    Queue
+	ErrorLog *log.Logger
+}
+
+// New handler of configuration
+func New(c Config, q Queue) *Handler {
+	return &Handler{Targets: c.Targets, Callers: c.Callers, Queue: q}
+}
+
+func (h *Handler) logf(format string, args ...interface{}) {
+	if h.ErrorLog != nil {
+		h.ErrorLog.Printf(format, args...)
+	}
+}
+
+// caller returns common name of verified client certificate
+func (h *Handler) caller(r *http.Request) (string, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return "", ErrCaller
+	}
+	cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	if len(h.Callers) == 0 {
+		return cn, nil
+	}
+	for _, c := range h.Callers {
+		if c == cn {
+			return cn, nil
+		}
+	}
+	return cn, ErrCaller
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cn, err := h.caller(r)
+	if err != nil {
+		h.logf("pwsync: %v %q: %v", r.RemoteAddr, cn, err)
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, MaxBody))
+	defer wipe(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	var req Request
+	if err := json.Unmarshal(body, &req); err != nil || req.User == "" || req.Password == "" {
+		http.Error(w, "user and password required", http.StatusBadRequest)
+		return
+	}
+	if !validUser(req.User) {
+		http.Error(w, "invalid user name", http.StatusBadRequest)
+		return
+	}
+	pass := []byte(req.Password)
+	defer wipe(pass)
+	results, status := h.change(req.User, pass)
+	h.logf("pwsync: %s changed %s: %s", cn, strings.ToUpper(req.User), http.StatusText(status))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(results)
+}
+
+// MaxUser is length of BNAME
+const MaxUser = 12
+
+// validUser reports whether name fits BNAME, so change files keep one
+// unquoted row per user
+func validUser(name string) bool {
+	if !utf8.ValidString(name) || utf8.RuneCountInString(name) > MaxUser {
+		return false
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// change validates password against all targets and queues change files
+// only if all accept it
+func (h *Handler) change(user string, pass []byte) ([]Result, int) {
+	results := make([]Result, len(h.Targets))
+	changes := make([]Change, len(h.Targets))
+	rejected := false
+	for i, t := range h.Targets {
+		results[i] = Result{System: t.System, Client: t.Client}
+		rec, err := t.Policy.Record(t.Client, user, pass)
+		if err == nil {
+			changes[i] = Change{System: t.System, Records: []usr02.Record{rec}}
+			continue
+		}
+		// errors beyond password rules, e.g. policy.ErrLegacy, have no explanation
+		if results[i].Reasons = t.Policy.Explain(pass, user); len(results[i].Reasons) == 0 {
+			results[i].Reasons = []string{err.Error()}
+		}
+		rejected = true
+	}
+	if rejected {
+		return results, http.StatusUnprocessableEntity
+	}
+	if _, err := h.Queue.Put(changes...); err != nil {
+		h.logf("pwsync: %v", err)
+		for i := range results {
+			results[i].Reasons = []string{"queue failed"}
+		}
+		return results, http.StatusInternalServerError
+	}
+	for i := range results {
+		results[i].Queued = true
+	}
+	return results, http.StatusOK
+}
+
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
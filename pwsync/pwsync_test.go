@@ -0,0 +1,224 @@
+package pwsync
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dim13/codvn/policy"
+	"github.com/dim13/codvn/usr02"
+)
+
+// issue certificate signed by parent, self-signed if parent is nil
+func issue(t *testing.T, cn string, parent *tls.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	signer, signerKey := tmpl, interface{}(key)
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestHandler(t *testing.T) {
+	ca := issue(t, "test CA", nil)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Leaf)
+	dir, err := ioutil.TempDir("", "pwsync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var c Config
+	if err := json.Unmarshal([]byte(`{
+		"targets": [
+			{"system": "PRD", "client": "100", "policy": {"kind": "SHA512", "iter": 1000}},
+			{"system": "QAS", "client": "200", "policy": {"kind": "SHA256", "iter": 500, "minLength": 16}}
+		],
+		"callers": ["directory"]
+	}`), &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Targets[0].Policy.MinScore != policy.Default.MinScore {
+		t.Fatalf("got %v, want default policy", c.Targets[0].Policy)
+	}
+	srv := httptest.NewUnstartedServer(New(c, Queue{Dir: dir}))
+	srv.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	srv.TLS = TLSConfig(issue(t, "localhost", &ca), pool)
+	srv.StartTLS()
+	defer srv.Close()
+
+	testCases := []struct {
+		name   string
+		caller string
+		req    Request
+		status int
+		queued int
+	}{
+		{name: "accepted", caller: "directory", req: Request{User: "jdoe", Password: "Tr0ub4dour&3-Horse!"}, status: http.StatusOK, queued: 2},
+		{name: "rejected by one", caller: "directory", req: Request{User: "jdoe", Password: "Kx7#mPq2vL9!"}, status: http.StatusUnprocessableEntity},
+		{name: "weak", caller: "directory", req: Request{User: "jdoe", Password: "Password1!"}, status: http.StatusUnprocessableEntity},
+		{name: "long user", caller: "directory", req: Request{User: "jdoe_with_long_name", Password: "Tr0ub4dour&3-Horse!"}, status: http.StatusBadRequest},
+		{name: "multi-line user", caller: "directory", req: Request{User: "jdoe\n100", Password: "Tr0ub4dour&3-Horse!"}, status: http.StatusBadRequest},
+		{name: "tab in user", caller: "directory", req: Request{User: "jd\toe", Password: "Tr0ub4dour&3-Horse!"}, status: http.StatusBadRequest},
+		{name: "no password", caller: "directory", req: Request{User: "jdoe"}, status: http.StatusBadRequest},
+		{name: "unknown caller", caller: "intruder", req: Request{User: "jdoe", Password: "Tr0ub4dour&3-Horse!"}, status: http.StatusForbidden},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+				RootCAs:      pool,
+				Certificates: []tls.Certificate{issue(t, tc.caller, &ca)},
+			}}}
+			body, _ := json.Marshal(tc.req)
+			resp, err := client.Post(srv.URL, "application/json", bytes.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != tc.status {
+				t.Fatalf("got %v, want %v: %s", resp.StatusCode, tc.status, data)
+			}
+			files, _ := filepath.Glob(filepath.Join(dir, "*", "*.tsv"))
+			if len(files) != tc.queued {
+				t.Fatalf("got %d change files, want %d", len(files), tc.queued)
+			}
+			for _, f := range files {
+				verifyChange(t, f, tc.req)
+			}
+			removeAll(t, files)
+		})
+	}
+
+	t.Run("no client certificate", func(t *testing.T) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+		if resp, err := client.Post(srv.URL, "application/json", strings.NewReader("{}")); err == nil {
+			resp.Body.Close()
+			t.Errorf("got %v, want handshake failure", resp.Status)
+		}
+	})
+}
+
+func verifyChange(t *testing.T, path string, req Request) {
+	t.Helper()
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte(req.Password)) {
+		t.Fatalf("%s: cleartext persisted", path)
+	}
+	recs, err := usr02.NewReader(bytes.NewReader(data), '\t').ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 1 {
+		t.Fatalf("got %d records, want 1", len(recs))
+	}
+	r := recs[0]
+	if r.User != strings.ToUpper(req.User) || r.Codvn != usr02.CodvnH {
+		t.Errorf("got %v %v, want %v H", r.User, r.Codvn, strings.ToUpper(req.User))
+	}
+	if err := r.PwdSaltedHash.Verify([]byte(req.Password)); err != nil {
+		t.Errorf("%s: %v", path, err)
+	}
+}
+
+func removeAll(t *testing.T, files []string) {
+	for _, f := range files {
+		if err := os.Remove(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLegacy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pwsync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var c Config
+	if err := json.Unmarshal([]byte(`{"targets": [
+		{"system": "PRD", "client": "100"},
+		{"system": "OLD", "client": "000", "policy": {"compat": 1}}
+	]}`), &c); err != nil {
+		t.Fatal(err)
+	}
+	results, status := New(c, Queue{Dir: dir}).change("jdoe", []byte("Tr0ub4dour&3-Horse!"))
+	if status != http.StatusUnprocessableEntity {
+		t.Fatalf("got %v, want %v", status, http.StatusUnprocessableEntity)
+	}
+	if r := results[1].Reasons; len(r) != 1 || r[0] != policy.ErrLegacy.Error() {
+		t.Errorf("got %q, want %q", r, policy.ErrLegacy)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*", "*")); len(files) != 0 {
+		t.Errorf("got %v, want no files", files)
+	}
+}
+
+func TestQueue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pwsync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// system directory can't be created
+	if err := ioutil.WriteFile(filepath.Join(dir, "BAD"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	rec := usr02.Record{Client: "100", User: "JDOE", Codvn: usr02.CodvnH}
+	if _, err := (Queue{Dir: dir}).Put(Change{"PRD", []usr02.Record{rec}}, Change{"BAD", []usr02.Record{rec}}); err == nil {
+		t.Fatal("got no error")
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "PRD", "*")); len(files) != 0 {
+		t.Errorf("got %v, want no files", files)
+	}
+	paths, err := (Queue{Dir: dir}).Put(Change{"PRD", []usr02.Record{rec}}, Change{"QAS", []usr02.Record{rec}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*", "*")); len(paths) != 2 || len(files) != 2 {
+		t.Errorf("got %v, %v, want 2 change files", paths, files)
+	}
+}
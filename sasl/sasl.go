@@ -0,0 +1,161 @@
+// Package sasl implements PLAIN and LOGIN server mechanisms verifying
+// credentials against CODVN H hashes
+//
+// Protocol servers feed client responses into Next until done, then use
+// Identity as authorized user:
+//
+//	s := sasl.NewPlain(store.Verifier(st, "100"), nil)
+//	for {
+//		challenge, done, err := s.Next(response)
+//		...
+//	}
+//
+// References:
+//   - https://tools.ietf.org/html/rfc4422
+//   - https://tools.ietf.org/html/rfc4616
+//   - https://tools.ietf.org/html/draft-murchison-sasl-login-00
+package sasl
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/dim13/codvn/store"
+)
+
+// Errors
+var (
+	ErrMalformed     = errors.New("malformed response")
+	ErrUnexpected    = errors.New("unexpected response")
+	ErrAuthFailed    = errors.New("authentication failed")
+	ErrNotAuthorized = errors.New("not authorized")
+)
+
+// Mechanism names
+const (
+	Plain = "PLAIN"
+	Login = "LOGIN"
+)
+
+// Server side of mechanism
+type Server interface {
+	// Next consumes client response and returns challenge, done is set
+	// after successful authentication
+	Next(response []byte) (challenge []byte, done bool, err error)
+	// Identity returns authorized identity once done
+	Identity() string
+}
+
+// Authorizer permits authenticated user to act as authorization identity
+type Authorizer func(authcid, authzid string) error
+
+// New server mechanism by name, authorizer applies to PLAIN only
+func New(name string, v store.UserVerifier, a Authorizer) (Server, bool) {
+	switch name {
+	case Plain:
+		return NewPlain(v, a), true
+	case Login:
+		return NewLogin(v), true
+	}
+	return nil, false
+}
+
+// Mechanisms supported
+func Mechanisms() []string {
+	return []string{Plain, Login}
+}
+
+type plain struct {
+	v        store.UserVerifier
+	a        Authorizer
+	asked    bool
+	done     bool
+	identity string
+}
+
+// NewPlain returns PLAIN mechanism, without authorizer users may act only
+// as themselves
+func NewPlain(v store.UserVerifier, a Authorizer) Server {
+	return &plain{v: v, a: a}
+}
+
+func (p *plain) Next(response []byte) ([]byte, bool, error) {
+	if p.done {
+		return nil, false, ErrUnexpected
+	}
+	if len(response) == 0 && !p.asked {
+		// no initial response, ask for it
+		p.asked = true
+		return []byte{}, false, nil
+	}
+	// one attempt only, failed or not
+	p.done = true
+	parts := bytes.Split(response, []byte{0})
+	if len(parts) != 3 || len(parts[1]) == 0 {
+		return nil, false, ErrMalformed
+	}
+	authzid, authcid, pass := string(parts[0]), string(parts[1]), parts[2]
+	if err := p.v.Verify(authcid, pass); err != nil {
+		return nil, false, ErrAuthFailed
+	}
+	if authzid == "" {
+		authzid = authcid
+	}
+	if authzid != authcid {
+		if p.a == nil {
+			return nil, false, ErrNotAuthorized
+		}
+		if err := p.a(authcid, authzid); err != nil {
+			return nil, false, ErrNotAuthorized
+		}
+	}
+	p.identity = authzid
+	return nil, true, nil
+}
+
+func (p *plain) Identity() string {
+	return p.identity
+}
+
+type login struct {
+	v        store.UserVerifier
+	step     int
+	user     string
+	identity string
+}
+
+// NewLogin returns LOGIN mechanism
+func NewLogin(v store.UserVerifier) Server {
+	return &login{v: v}
+}
+
+func (l *login) Next(response []byte) ([]byte, bool, error) {
+	switch l.step {
+	case 0:
+		l.step++
+		if len(response) == 0 {
+			return []byte("Username:"), false, nil
+		}
+		// initial response carries user name
+		fallthrough
+	case 1:
+		if len(response) == 0 {
+			return nil, false, ErrMalformed
+		}
+		l.user = string(response)
+		l.step = 2
+		return []byte("Password:"), false, nil
+	case 2:
+		l.step++
+		if err := l.v.Verify(l.user, response); err != nil {
+			return nil, false, ErrAuthFailed
+		}
+		l.identity = l.user
+		return nil, true, nil
+	}
+	return nil, false, ErrUnexpected
+}
+
+func (l *login) Identity() string {
+	return l.identity
+}
@@ -0,0 +1,226 @@
+package sasl
+
+import (
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/dim13/codvn"
+	"github.com/dim13/codvn/store"
+)
+
+func testStore(t *testing.T) *store.Store {
+	s := &store.Store{}
+	for user, pass := range map[string]string{"ALICE": "Pindakaas!", "BOB": "HashCat!"} {
+		h, err := codvn.New(codvn.SHA512, []byte(pass), []byte("0123456789abcdef"), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Put("100", user, h); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+// serve minimal SMTP AUTH, reports authorized identity
+func serve(conn net.Conn, v store.UserVerifier, a Authorizer, identity chan<- string) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		switch strings.ToUpper(fields[0]) {
+		case "EHLO":
+			tp.PrintfLine("250-localhost")
+			tp.PrintfLine("250 AUTH %s", strings.Join(Mechanisms(), " "))
+		case "AUTH":
+			s, ok := New(fields[1], v, a)
+			if !ok {
+				tp.PrintfLine("504 5.5.4 unrecognized authentication type")
+				continue
+			}
+			var resp []byte
+			if len(fields) > 2 && fields[2] != "=" {
+				resp, _ = base64.StdEncoding.DecodeString(fields[2])
+			}
+			for {
+				challenge, done, err := s.Next(resp)
+				if err != nil {
+					tp.PrintfLine("535 5.7.8 %v", err)
+					identity <- ""
+					break
+				}
+				if done {
+					tp.PrintfLine("235 2.7.0 authenticated")
+					identity <- s.Identity()
+					break
+				}
+				tp.PrintfLine("334 %s", base64.StdEncoding.EncodeToString(challenge))
+				line, err := tp.ReadLine()
+				if err != nil {
+					return
+				}
+				resp, _ = base64.StdEncoding.DecodeString(line)
+			}
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 5.5.2 unknown command")
+		}
+	}
+}
+
+// loginAuth is LOGIN client, net/smtp has none
+type loginAuth struct {
+	user, pass string
+}
+
+func (a loginAuth) Start(*smtp.ServerInfo) (string, []byte, error) {
+	return Login, nil, nil
+}
+
+func (a loginAuth) Next(challenge []byte, more bool) ([]byte, error) {
+	switch string(challenge) {
+	case "Username:":
+		return []byte(a.user), nil
+	case "Password:":
+		return []byte(a.pass), nil
+	}
+	if more {
+		return nil, errors.New("unexpected challenge")
+	}
+	return nil, nil
+}
+
+func TestSMTP(t *testing.T) {
+	st := testStore(t)
+	admins := func(authcid, authzid string) error {
+		if authcid != "ALICE" {
+			return errors.New("no")
+		}
+		return nil
+	}
+	testCases := []struct {
+		title    string
+		auth     smtp.Auth
+		identity string
+	}{
+		{title: "plain", auth: smtp.PlainAuth("", "alice", "Pindakaas!", "localhost"), identity: "alice"},
+		{title: "plain authzid", auth: smtp.PlainAuth("BOB", "ALICE", "Pindakaas!", "localhost"), identity: "BOB"},
+		{title: "plain not authorized", auth: smtp.PlainAuth("ALICE", "BOB", "HashCat!", "localhost")},
+		{title: "plain wrong", auth: smtp.PlainAuth("", "ALICE", "HashCat!", "localhost")},
+		{title: "plain unknown", auth: smtp.PlainAuth("", "MALLORY", "HashCat!", "localhost")},
+		{title: "login", auth: loginAuth{"BOB", "HashCat!"}, identity: "BOB"},
+		{title: "login wrong", auth: loginAuth{"BOB", "Pindakaas!"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			server, client := net.Pipe()
+			identity := make(chan string, 1)
+			go serve(server, store.Verifier(st, "100"), admins, identity)
+			c, err := smtp.NewClient(client, "localhost")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			err = c.Auth(tc.auth)
+			if (err == nil) != (tc.identity != "") {
+				t.Fatalf("got %v", err)
+			}
+			if got := <-identity; got != tc.identity {
+				t.Errorf("got identity %q, want %q", got, tc.identity)
+			}
+			c.Quit()
+		})
+	}
+}
+
+func TestPlain(t *testing.T) {
+	v := store.Verifier(testStore(t), "100")
+	testCases := []struct {
+		title     string
+		responses []string
+		err       error
+	}{
+		{title: "no initial response", responses: []string{"", "\x00ALICE\x00Pindakaas!"}},
+		{title: "malformed", responses: []string{"ALICE\x00Pindakaas!"}, err: ErrMalformed},
+		{title: "empty authcid", responses: []string{"\x00\x00Pindakaas!"}, err: ErrMalformed},
+		{title: "twice empty", responses: []string{"", ""}, err: ErrMalformed},
+		{title: "extra step", responses: []string{"\x00ALICE\x00Pindakaas!", ""}, err: ErrUnexpected},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			s := NewPlain(v, nil)
+			var err error
+			for _, r := range tc.responses {
+				if _, _, err = s.Next([]byte(r)); err != nil {
+					break
+				}
+			}
+			if err != tc.err {
+				t.Errorf("got %v, want %v", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestPlainRetry(t *testing.T) {
+	testCases := []struct {
+		title string
+		first string
+		err   error
+	}{
+		{title: "wrong password", first: "\x00ALICE\x00Pindakaas?", err: ErrAuthFailed},
+		{title: "malformed", first: "ALICE\x00Pindakaas!", err: ErrMalformed},
+		{title: "not authorized", first: "BOB\x00ALICE\x00Pindakaas!", err: ErrNotAuthorized},
+	}
+	v := store.Verifier(testStore(t), "100")
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			s := NewPlain(v, nil)
+			if _, _, err := s.Next([]byte(tc.first)); err != tc.err {
+				t.Fatalf("got %v, want %v", err, tc.err)
+			}
+			// no second guess, even with right password
+			if _, _, err := s.Next([]byte("\x00ALICE\x00Pindakaas!")); err != ErrUnexpected {
+				t.Errorf("got %v, want %v", err, ErrUnexpected)
+			}
+			if s.Identity() != "" {
+				t.Errorf("got identity %q", s.Identity())
+			}
+		})
+	}
+}
+
+func TestLoginInitialResponse(t *testing.T) {
+	s := NewLogin(store.Verifier(testStore(t), "100"))
+	challenge, done, err := s.Next([]byte("ALICE"))
+	if err != nil || done || string(challenge) != "Password:" {
+		t.Fatalf("got %q %v %v", challenge, done, err)
+	}
+	if _, done, err = s.Next([]byte("Pindakaas!")); err != nil || !done || s.Identity() != "ALICE" {
+		t.Fatalf("got %v %v %q", done, err, s.Identity())
+	}
+	if _, _, err = s.Next(nil); err != ErrUnexpected {
+		t.Errorf("got %v, want %v", err, ErrUnexpected)
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	st := testStore(t)
+	st.Verifier = codvn.Verifier{MinIter: 10000}
+	s := NewPlain(store.Verifier(st, "100"), nil)
+	if _, _, err := s.Next([]byte("\x00ALICE\x00Pindakaas!")); err != ErrAuthFailed {
+		t.Errorf("got %v, want %v", err, ErrAuthFailed)
+	}
+}
@@ -0,0 +1,143 @@
+// Package seal encrypts secrets for a recipient RSA public key
+//
+// A random AES-256 key encrypts the secret with GCM, the key itself is
+// encrypted with RSA-OAEP (SHA-256). Sealed secrets are PEM encoded:
+//
+//	-----BEGIN SEALED SECRET-----
+//	base64(len(key) . rsa(key) . nonce . gcm(secret))
+//	-----END SEALED SECRET-----
+package seal
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"io"
+)
+
+// Errors
+var (
+	ErrFormat = errors.New("malformed sealed secret")
+	ErrKey    = errors.New("not an RSA key")
+)
+
+// Type of PEM block
+const Type = "SEALED SECRET"
+
+// Seal secret for recipient, headers are authenticated but not encrypted
+func Seal(pub *rsa.PublicKey, secret []byte, headers map[string]string) ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	wrapped, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, key, []byte(Type))
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	out := make([]byte, 2, 2+len(wrapped)+len(nonce)+len(secret)+gcm.Overhead())
+	binary.BigEndian.PutUint16(out, uint16(len(wrapped)))
+	out = append(out, wrapped...)
+	out = append(out, nonce...)
+	out = gcm.Seal(out, nonce, secret, additional(headers))
+	return pem.EncodeToMemory(&pem.Block{Type: Type, Headers: headers, Bytes: out}), nil
+}
+
+// Open sealed secret, returns secret and headers
+func Open(priv *rsa.PrivateKey, data []byte) ([]byte, map[string]string, error) {
+	b, _ := pem.Decode(data)
+	if b == nil || b.Type != Type || len(b.Bytes) < 2 {
+		return nil, nil, ErrFormat
+	}
+	in := b.Bytes
+	n := int(binary.BigEndian.Uint16(in))
+	if len(in) < 2+n {
+		return nil, nil, ErrFormat
+	}
+	key, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, priv, in[2:2+n], []byte(Type))
+	if err != nil {
+		return nil, nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	in = in[2+n:]
+	if len(in) < gcm.NonceSize() {
+		return nil, nil, ErrFormat
+	}
+	secret, err := gcm.Open(nil, in[:gcm.NonceSize()], in[gcm.NonceSize():], additional(b.Headers))
+	if err != nil {
+		return nil, nil, err
+	}
+	return secret, b.Headers, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// additional data binds headers, as PEM encodes them
+func additional(headers map[string]string) []byte {
+	if len(headers) == 0 {
+		return nil
+	}
+	return pem.EncodeToMemory(&pem.Block{Headers: headers})
+}
+
+// ParsePublicKey parses PEM encoded PKIX or PKCS #1 RSA public key
+func ParsePublicKey(data []byte) (*rsa.PublicKey, error) {
+	b, _ := pem.Decode(data)
+	if b == nil {
+		return nil, ErrKey
+	}
+	if pub, err := x509.ParsePKCS1PublicKey(b.Bytes); err == nil {
+		return pub, nil
+	}
+	key, err := x509.ParsePKIXPublicKey(b.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, ErrKey
+	}
+	return pub, nil
+}
+
+// ParsePrivateKey parses PEM encoded PKCS #8 or PKCS #1 RSA private key
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	b, _ := pem.Decode(data)
+	if b == nil {
+		return nil, ErrKey
+	}
+	if priv, err := x509.ParsePKCS1PrivateKey(b.Bytes); err == nil {
+		return priv, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(b.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	priv, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, ErrKey
+	}
+	return priv, nil
+}
@@ -0,0 +1,55 @@
+package seal
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+)
+
+func TestSeal(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv2, err := ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(priv)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret := []byte(`hX4#qv9!Lm2pZ8`)
+	headers := map[string]string{"Client": "001", "User": "RFC_USER"}
+	sealed, err := Seal(pub, secret, headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed, secret) {
+		t.Fatal("secret in clear")
+	}
+	got, h, err := Open(priv2, sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, secret) || h["User"] != "RFC_USER" {
+		t.Errorf("got %q %v", got, h)
+	}
+
+	// tampered header
+	tampered := bytes.Replace(sealed, []byte("RFC_USER"), []byte("SAP*"), 1)
+	if _, _, err := Open(priv, tampered); err == nil {
+		t.Error("tampered header accepted")
+	}
+	if _, _, err := Open(priv, []byte("junk")); err != ErrFormat {
+		t.Errorf("got %v, want %v", err, ErrFormat)
+	}
+}
@@ -0,0 +1,165 @@
+// Package shamir implements Shamir's secret sharing over GF(256)
+//
+// Every byte of the secret is split independently with a random polynomial
+// of degree k-1, any k of n shares recover the secret.
+//
+// Share text format:
+//
+//	k-x-hex(y)
+//
+// References:
+//   - https://dl.acm.org/doi/10.1145/359168.359176
+package shamir
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Errors
+var (
+	ErrThreshold = errors.New("invalid threshold")
+	ErrShares    = errors.New("not enough shares")
+	ErrMismatch  = errors.New("shares don't match")
+	ErrDuplicate = errors.New("duplicate share")
+	ErrFormat    = errors.New("malformed share")
+)
+
+// Share of a secret
+type Share struct {
+	K int    // threshold
+	X byte   // x coordinate, never zero
+	Y []byte // polynomial values
+}
+
+func (s Share) String() string {
+	return fmt.Sprintf("%d-%d-%x", s.K, s.X, s.Y)
+}
+
+// MarshalText encodes share
+func (s Share) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText parses share
+func (s *Share) UnmarshalText(text []byte) error {
+	var y string
+	if _, err := fmt.Sscanf(string(text), "%d-%d-%s", &s.K, &s.X, &y); err != nil {
+		return ErrFormat
+	}
+	if s.K < 2 || s.X == 0 {
+		return ErrFormat
+	}
+	b, err := hex.DecodeString(y)
+	if err != nil {
+		return ErrFormat
+	}
+	s.Y = b
+	return nil
+}
+
+// Parse share
+func Parse(text []byte) (Share, error) {
+	var s Share
+	err := s.UnmarshalText(text)
+	return s, err
+}
+
+// Split secret into n shares, any k of them recover it
+func Split(secret []byte, k, n int, rand io.Reader) ([]Share, error) {
+	if k < 2 || n < k || n > 255 {
+		return nil, ErrThreshold
+	}
+	shares := make([]Share, n)
+	for i := range shares {
+		shares[i] = Share{K: k, X: byte(i + 1), Y: make([]byte, len(secret))}
+	}
+	coef := make([]byte, k)
+	for j, b := range secret {
+		coef[0] = b
+		if _, err := io.ReadFull(rand, coef[1:]); err != nil {
+			return nil, err
+		}
+		for i := range shares {
+			shares[i].Y[j] = eval(coef, shares[i].X)
+		}
+	}
+	for i := range coef {
+		coef[i] = 0
+	}
+	return shares, nil
+}
+
+// Combine shares into secret
+func Combine(shares []Share) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, ErrShares
+	}
+	k, size := shares[0].K, len(shares[0].Y)
+	if len(shares) < k {
+		return nil, ErrShares
+	}
+	shares = shares[:k]
+	seen := make(map[byte]bool)
+	for _, s := range shares {
+		if s.K != k || len(s.Y) != size {
+			return nil, ErrMismatch
+		}
+		if s.X == 0 || seen[s.X] {
+			return nil, ErrDuplicate
+		}
+		seen[s.X] = true
+	}
+	secret := make([]byte, size)
+	for i, s := range shares {
+		// Lagrange basis polynomial at x=0
+		l := byte(1)
+		for j, o := range shares {
+			if i != j {
+				l = mul(l, div(o.X, o.X^s.X))
+			}
+		}
+		for n, y := range s.Y {
+			secret[n] ^= mul(l, y)
+		}
+	}
+	return secret, nil
+}
+
+// eval polynomial at x (Horner)
+func eval(coef []byte, x byte) byte {
+	var y byte
+	for i := len(coef) - 1; i >= 0; i-- {
+		y = mul(y, x) ^ coef[i]
+	}
+	return y
+}
+
+var expTable, logTable [256]byte
+
+func init() {
+	// generator 3 over x^8 + x^4 + x^3 + x + 1
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		expTable[i] = x
+		logTable[x] = byte(i)
+		x ^= x<<1 ^ (x>>7)*0x1b
+	}
+	expTable[255] = expTable[0]
+}
+
+func mul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return expTable[(int(logTable[a])+int(logTable[b]))%255]
+}
+
+func div(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return expTable[(int(logTable[a])-int(logTable[b])+255)%255]
+}
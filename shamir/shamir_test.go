@@ -0,0 +1,76 @@
+package shamir
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestShamir(t *testing.T) {
+	secret := []byte(`Pindakaas!`)
+	testCases := []struct {
+		title string
+		k, n  int
+		pick  []int
+		err   error
+	}{
+		{title: "2of3", k: 2, n: 3, pick: []int{0, 2}},
+		{title: "3of5", k: 3, n: 5, pick: []int{4, 1, 3}},
+		{title: "all", k: 5, n: 5, pick: []int{0, 1, 2, 3, 4}},
+		{title: "extra", k: 2, n: 5, pick: []int{3, 0, 1}},
+		{title: "short", k: 3, n: 5, pick: []int{0, 1}, err: ErrShares},
+		{title: "duplicate", k: 2, n: 3, pick: []int{1, 1}, err: ErrDuplicate},
+		{title: "none", k: 2, n: 3, err: ErrShares},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			shares, err := Split(secret, tc.k, tc.n, rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var picked []Share
+			for _, i := range tc.pick {
+				// round trip through text
+				s, err := Parse([]byte(shares[i].String()))
+				if err != nil {
+					t.Fatal(err)
+				}
+				picked = append(picked, s)
+			}
+			got, err := Combine(picked)
+			if err != tc.err {
+				t.Fatalf("got %v, want %v", err, tc.err)
+			}
+			if tc.err != nil {
+				return
+			}
+			if !bytes.Equal(got, secret) {
+				t.Errorf("got %q, want %q", got, secret)
+			}
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	for _, kn := range [][2]int{{1, 3}, {3, 2}, {2, 256}} {
+		if _, err := Split([]byte("x"), kn[0], kn[1], rand.Reader); err != ErrThreshold {
+			t.Errorf("k=%d n=%d: got %v, want %v", kn[0], kn[1], err, ErrThreshold)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, s := range []string{"", "2-0-abcd", "1-1-abcd", "2-1-xyz", "2-abcd"} {
+		if _, err := Parse([]byte(s)); err != ErrFormat {
+			t.Errorf("%q: got %v, want %v", s, err, ErrFormat)
+		}
+	}
+}
+
+func TestMismatch(t *testing.T) {
+	a, _ := Split([]byte("abc"), 2, 3, rand.Reader)
+	b, _ := Split([]byte("abcd"), 2, 3, rand.Reader)
+	if _, err := Combine([]Share{a[0], b[1]}); err != ErrMismatch {
+		t.Errorf("got %v, want %v", err, ErrMismatch)
+	}
+}
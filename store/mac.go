@@ -0,0 +1,157 @@
+package store
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Key authenticating store entries
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+func (k Key) String() string {
+	return fmt.Sprintf("%s %x", k.ID, k.Secret)
+}
+
+// NewKey generates random key
+func NewKey(id string) (Key, error) {
+	secret := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(rand.Reader, secret); err != nil {
+		return Key{}, err
+	}
+	return Key{ID: id, Secret: secret}, nil
+}
+
+// LoadKeys reads key file, one "id hex(secret)" per line, the last key is
+// current and signs new entries, older keys are kept for rotation
+func LoadKeys(path string) ([]Key, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var keys []Key
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, ErrKeyFormat
+		}
+		secret, err := hex.DecodeString(fields[1])
+		if err != nil || len(secret) == 0 {
+			return nil, ErrKeyFormat
+		}
+		keys = append(keys, Key{ID: fields[0], Secret: secret})
+	}
+	return keys, sc.Err()
+}
+
+// mac binds client, user, hash and version of entry
+func (k Key) mac(e Entry) []byte {
+	m := hmac.New(sha256.New, k.Secret)
+	for _, s := range []string{e.Client, e.User, e.Hash.String()} {
+		binary.Write(m, binary.BigEndian, uint32(len(s)))
+		io.WriteString(m, s)
+	}
+	binary.Write(m, binary.BigEndian, uint64(e.Version))
+	return m.Sum(nil)
+}
+
+func (s *Store) key(id string) (Key, bool) {
+	for _, k := range s.Keys {
+		if k.ID == id {
+			return k, true
+		}
+	}
+	return Key{}, false
+}
+
+// sign entry with current key
+func (s *Store) sign(e *Entry) {
+	if len(s.Keys) == 0 {
+		return
+	}
+	k := s.Keys[len(s.Keys)-1]
+	e.KeyID, e.MAC = k.ID, k.mac(*e)
+}
+
+// signed reports whether any entry carries MAC
+func (s *Store) signed() bool {
+	for _, e := range s.Entries {
+		if e.KeyID != "" || e.MAC != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// authentic checks entry MAC, unsigned entries are authentic without keys
+func (s *Store) authentic(e Entry) error {
+	if len(s.Keys) == 0 {
+		if e.KeyID != "" || e.MAC != nil {
+			return ErrNoKeys
+		}
+		return nil
+	}
+	k, ok := s.key(e.KeyID)
+	if !ok {
+		return ErrUnknownKey
+	}
+	if !hmac.Equal(k.mac(e), e.MAC) {
+		return ErrIntegrity
+	}
+	return nil
+}
+
+// IntegrityError of entry
+type IntegrityError struct {
+	Client, User string
+	Err          error
+}
+
+func (e IntegrityError) Error() string {
+	return fmt.Sprintf("%s/%s: %v", e.Client, e.User, e.Err)
+}
+
+// Check integrity of all entries
+func (s *Store) Check() []IntegrityError {
+	var errs []IntegrityError
+	for _, e := range s.Entries {
+		if err := s.authentic(e); err != nil {
+			errs = append(errs, IntegrityError{Client: e.Client, User: e.User, Err: err})
+		}
+	}
+	return errs
+}
+
+// Rotate re-signs authentic entries with current key, unsigned entries are
+// signed only if adopt is set, entries failing integrity are left as they
+// are and reported
+func (s *Store) Rotate(adopt bool) []IntegrityError {
+	var errs []IntegrityError
+	for i, e := range s.Entries {
+		if e.MAC == nil && adopt {
+			s.sign(&s.Entries[i])
+			continue
+		}
+		if err := s.authentic(e); err != nil {
+			errs = append(errs, IntegrityError{Client: e.Client, User: e.User, Err: err})
+			continue
+		}
+		s.sign(&s.Entries[i])
+	}
+	return errs
+}
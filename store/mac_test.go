@@ -0,0 +1,136 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dim13/codvn"
+)
+
+func testStore(t *testing.T, keys ...Key) *Store {
+	s := &Store{Keys: keys}
+	for _, u := range []string{"SAP*", "DDIC"} {
+		h, err := codvn.New(codvn.SHA256, []byte(u), []byte(`salt`), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Put("001", u, h); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+func TestIntegrity(t *testing.T) {
+	k1, _ := NewKey("k1")
+	s := testStore(t, k1)
+	if errs := s.Check(); len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	// substitute hash of SAP* by hash of DDIC
+	s.Entries[0].Hash = s.Entries[1].Hash
+	if _, err := s.Get("001", "SAP*"); err != ErrIntegrity {
+		t.Errorf("got %v, want %v", err, ErrIntegrity)
+	}
+	if err := s.Verify("001", "SAP*", []byte(`DDIC`)); err != ErrIntegrity {
+		t.Errorf("got %v, want %v", err, ErrIntegrity)
+	}
+	if errs := s.Check(); len(errs) != 1 || errs[0].User != "SAP*" {
+		t.Errorf("got %v", errs)
+	}
+
+	// version changed without MAC
+	s = testStore(t, k1)
+	s.Entries[1].Version--
+	if _, err := s.Get("001", "DDIC"); err != ErrIntegrity {
+		t.Errorf("got %v, want %v", err, ErrIntegrity)
+	}
+
+	// signed entries read without keys
+	s.Keys = nil
+	if _, err := s.Get("001", "SAP*"); err != ErrNoKeys {
+		t.Errorf("got %v, want %v", err, ErrNoKeys)
+	}
+
+	// signed by foreign key
+	k2, _ := NewKey("k2")
+	s.Keys = []Key{k2}
+	if _, err := s.Get("001", "SAP*"); err != ErrUnknownKey {
+		t.Errorf("got %v, want %v", err, ErrUnknownKey)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	k1, _ := NewKey("k1")
+	k2, _ := NewKey("k2")
+	s := testStore(t)
+	s.Keys = []Key{k1}
+	if errs := s.Check(); len(errs) != 2 {
+		t.Fatalf("got %v, want unsigned entries", errs)
+	}
+	if errs := s.Rotate(true); len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	s.Entries[1].User = "DDIC2"
+	s.Keys = []Key{k1, k2}
+	if errs := s.Rotate(false); len(errs) != 1 || errs[0].Err != ErrIntegrity {
+		t.Fatalf("got %v", errs)
+	}
+	s.Keys = []Key{k2}
+	if e, err := s.Get("001", "SAP*"); err != nil || e.KeyID != "k2" {
+		t.Errorf("got %v %v", e.KeyID, err)
+	}
+}
+
+func TestLoadKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "keys")
+	k1, _ := NewKey("k1")
+	k2, _ := NewKey("k2")
+	data := "# store keys\n" + k1.String() + "\n\n" + k2.String() + "\n"
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := LoadKeys(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[1].String() != k2.String() {
+		t.Errorf("got %v", keys)
+	}
+	if err := ioutil.WriteFile(path, []byte("k1 zz\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadKeys(path); err != ErrKeyFormat {
+		t.Errorf("got %v, want %v", err, ErrKeyFormat)
+	}
+}
+
+func TestPut(t *testing.T) {
+	k1, _ := NewKey("k1")
+	h := testStore(t).Entries[0].Hash
+
+	// unsigned entry replacing signed ones
+	s := testStore(t, k1)
+	s.Keys = nil
+	if _, err := s.Put("001", "NEW", h); err != ErrNoKeys {
+		t.Errorf("got %v, want %v", err, ErrNoKeys)
+	}
+
+	// tampered version carried forward
+	s = testStore(t, k1)
+	s.Entries[0].Version = 41
+	if _, err := s.Put("001", "SAP*", h); err != ErrIntegrity {
+		t.Errorf("got %v, want %v", err, ErrIntegrity)
+	}
+	if e, err := s.Put("001", "DDIC", h); err != nil || e.Version != 2 {
+		t.Errorf("got %v %v, want version 2", e.Version, err)
+	}
+}
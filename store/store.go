@@ -0,0 +1,208 @@
+// Package store keeps CODVN H hashes of users per client
+//
+// The store is a JSON file, cleartext passwords are never kept. With keys
+// configured every entry carries HMAC-SHA256 over client, user, hash and
+// version, so a hash replaced by another valid-looking one is detected on
+// read. Signed entries can't be read without keys.
+//
+// The MAC doesn't protect against rollback: an older entry copied back
+// with its own valid MAC, or an older copy of the whole store, is accepted.
+// Detecting that requires the latest versions to be kept outside the store.
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dim13/codvn"
+)
+
+// Errors
+var (
+	ErrNotFound   = errors.New("user not found")
+	ErrIntegrity  = errors.New("entry integrity violated")
+	ErrUnknownKey = errors.New("entry signed by unknown key")
+	ErrKeyFormat  = errors.New("malformed key")
+	ErrNoKeys     = errors.New("entry signed but no keys loaded")
+)
+
+// Entry of store
+type Entry struct {
+	Client  string      `json:"client"`
+	User    string      `json:"user"`
+	Hash    codvn.CodvN `json:"hash"`
+	Version int         `json:"version"`
+	Changed time.Time   `json:"changed"`
+	KeyID   string      `json:"key,omitempty"`
+	MAC     []byte      `json:"mac,omitempty"`
+}
+
+// Event of store history
+type Event struct {
+	Time    time.Time `json:"time"`
+	Client  string    `json:"client"`
+	User    string    `json:"user"`
+	Version int       `json:"version"`
+	Action  string    `json:"action"`
+	Note    string    `json:"note,omitempty"`
+}
+
+// Store of credentials
+type Store struct {
+	path    string
+	Entries []Entry `json:"entries"`
+	History []Event `json:"history,omitempty"`
+
+	// Verifier rejects stored hashes weaker than policy
+	Verifier codvn.Verifier `json:"-"`
+	// Keys authenticating entries, last one is current
+	Keys []Key `json:"-"`
+}
+
+// Open store file, missing file results in empty store
+func Open(path string) (*Store, error) {
+	s := &Store{path: path}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Save store atomically
+func (s *Store) Save() error {
+	data, err := json.MarshalIndent(s, "", "\t")
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(s.path), ".store")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path)
+}
+
+func (s *Store) index(client, user string) int {
+	user = strings.ToUpper(user)
+	for i, e := range s.Entries {
+		if e.Client == client && e.User == user {
+			return i
+		}
+	}
+	return -1
+}
+
+// Get authentic entry of user
+func (s *Store) Get(client, user string) (Entry, error) {
+	i := s.index(client, user)
+	if i < 0 {
+		return Entry{}, ErrNotFound
+	}
+	e := s.Entries[i]
+	if err := s.authentic(e); err != nil {
+		return Entry{}, err
+	}
+	return e, nil
+}
+
+// Put hash of user, replaces existing entry and bumps its version. The
+// replaced entry must be authentic, and a store holding signed entries
+// requires keys, so signed entries are never replaced by unsigned ones.
+func (s *Store) Put(client, user string, hash codvn.CodvN) (Entry, error) {
+	if len(s.Keys) == 0 && s.signed() {
+		return Entry{}, ErrNoKeys
+	}
+	e := Entry{
+		Client:  client,
+		User:    strings.ToUpper(user),
+		Hash:    hash,
+		Version: 1,
+		Changed: time.Now().UTC(),
+	}
+	i := s.index(client, user)
+	if i >= 0 {
+		if err := s.authentic(s.Entries[i]); err != nil {
+			return Entry{}, err
+		}
+		e.Version = s.Entries[i].Version + 1
+	}
+	s.sign(&e)
+	if i >= 0 {
+		s.Entries[i] = e
+	} else {
+		s.Entries = append(s.Entries, e)
+	}
+	return e, nil
+}
+
+// Log action on entry to history
+func (s *Store) Log(e Entry, action, note string) {
+	s.History = append(s.History, Event{
+		Time:    e.Changed,
+		Client:  e.Client,
+		User:    e.User,
+		Version: e.Version,
+		Action:  action,
+		Note:    note,
+	})
+}
+
+// Events of user history, oldest first
+func (s *Store) Events(client, user string) []Event {
+	user = strings.ToUpper(user)
+	var events []Event
+	for _, e := range s.History {
+		if e.Client == client && e.User == user {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// Verify password of user
+func (s *Store) Verify(client, user string, pass []byte) error {
+	e, err := s.Get(client, user)
+	if err != nil {
+		return err
+	}
+	return s.Verifier.Verify(e.Hash, pass)
+}
+
+// UserVerifier checks password of user within one client
+type UserVerifier interface {
+	Verify(user string, pass []byte) error
+}
+
+// UserVerifierFunc adapts function to UserVerifier
+type UserVerifierFunc func(user string, pass []byte) error
+
+// Verify password of user
+func (f UserVerifierFunc) Verify(user string, pass []byte) error {
+	return f(user, pass)
+}
+
+// Verifier of users of client in store
+func Verifier(s *Store, client string) UserVerifier {
+	return UserVerifierFunc(func(user string, pass []byte) error {
+		return s.Verify(client, user, pass)
+	})
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dim13/codvn"
+)
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "store.json")
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := codvn.New(codvn.SHA256, []byte(`Pindakaas!`), []byte(`salt`), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := s.Put("001", "sap*", h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Log(e, "create", "")
+	if e, err = s.Put("001", "SAP*", h); err != nil {
+		t.Fatal(err)
+	}
+	if e.Version != 2 {
+		t.Errorf("got version %d, want 2", e.Version)
+	}
+	s.Log(e, "rotate", "RFC destination")
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(s.Entries))
+	}
+	if ev := s.Events("001", "sap*"); len(ev) != 2 || ev[1].Action != "rotate" || ev[1].Version != 2 {
+		t.Errorf("got %+v", ev)
+	}
+	if err := s.Verify("001", "sap*", []byte(`Pindakaas!`)); err != nil {
+		t.Error(err)
+	}
+	if err := s.Verify("001", "SAP*", []byte(`wrong`)); err != codvn.ErrDontMatch {
+		t.Errorf("got %v, want %v", err, codvn.ErrDontMatch)
+	}
+	if err := s.Verify("000", "SAP*", []byte(`Pindakaas!`)); err != ErrNotFound {
+		t.Errorf("got %v, want %v", err, ErrNotFound)
+	}
+	s.Verifier = codvn.Verifier{Kinds: []codvn.Kind{codvn.SHA512}}
+	if err := s.Verify("001", "SAP*", []byte(`Pindakaas!`)); err != codvn.ErrPolicy {
+		t.Errorf("got %v, want %v", err, codvn.ErrPolicy)
+	}
+}
@@ -0,0 +1,40 @@
+package strength
+
+import "strings"
+
+// common passwords and words, most frequent first
+const common = `
+password 123456 12345678 qwerty 123456789 12345 1234 111111 1234567 dragon
+123123 baseball abc123 football monkey letmein 696969 shadow master 666666
+qwertyuiop 123321 mustang 1234567890 michael 654321 superman 1qaz2wsx 7777777
+121212 000000 qazwsx 123qwe killer trustno1 jordan jennifer zxcvbnm asdfgh
+hunter buster soccer harley batman andrew tigger sunshine iloveyou 2000
+charlie robert thomas hockey ranger daniel starwars klaster 112233 george
+computer michelle jessica pepper 1111 zxcvbn 555555 11111111 131313 freedom
+777777 pass maggie 159753 aaaaaa ginger princess joshua cheese amanda summer
+love ashley nicole chelsea biteme matthew access yankees 987654321 dallas
+austin thunder taylor matrix welcome secret admin administrator changeme
+initial passwort geheim hallo sommer winter fruehling herbst frühling
+spring autumn fall start test test123 testtest guest default system manager
+support service user sap sapsap saprouter ddic sapcpic tmsadm earlywatch
+solman basis hana abap netweaver 06071992 19920706 pass1234 abcd1234
+company business office london berlin paris hamburg munich muenchen
+january february march april may june july august september october
+november december januar februar maerz märz mai juni juli oktober dezember
+monday tuesday wednesday thursday friday saturday sunday montag dienstag
+mittwoch donnerstag freitag samstag sonntag hello world secure login
+letmein123 qwertz asdf yxcvbnm hallo123 schatz engel blume sonne mond stern
+liebe dragon123 football1 baseball1 welcome1 password1 passw0rd p@ssword
+`
+
+var dictionary = rank(common)
+
+func rank(s string) map[string]int {
+	m := make(map[string]int)
+	for i, w := range strings.Fields(s) {
+		if _, ok := m[w]; !ok {
+			m[w] = i + 1
+		}
+	}
+	return m
+}
@@ -0,0 +1,319 @@
+package strength
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Pattern of match
+type Pattern string
+
+// Patterns
+const (
+	Dictionary Pattern = "dictionary"
+	UserInput  Pattern = "user input"
+	Keyboard   Pattern = "keyboard"
+	Date       Pattern = "date"
+	Repeat     Pattern = "repeat"
+	Sequence   Pattern = "sequence"
+	Bruteforce Pattern = "bruteforce"
+)
+
+// Match of pattern in password
+type Match struct {
+	Pattern Pattern
+	Token   string
+	I, J    int     // rune offsets, token is pass[I:J]
+	Guesses float64 // guesses needed to find token
+	L33t    bool    // common substitutions were used
+}
+
+// minimal guesses of a non-bruteforce match
+const minGuesses = 10
+
+var l33t = map[rune][]rune{
+	'4': {'a'}, '@': {'a'}, '3': {'e'}, '1': {'i', 'l'}, '!': {'i'},
+	'0': {'o'}, '$': {'s'}, '5': {'s'}, '7': {'t'}, '+': {'t'},
+}
+
+// unleet returns variants of token with common substitutions reverted
+func unleet(token []rune) []string {
+	variants := []string{""}
+	for _, r := range token {
+		subs, ok := l33t[r]
+		if !ok {
+			subs = []rune{r}
+		}
+		var next []string
+		for _, v := range variants {
+			for _, s := range subs {
+				next = append(next, v+string(s))
+			}
+		}
+		if len(next) > 16 {
+			next = next[:16]
+		}
+		variants = next
+	}
+	return variants
+}
+
+// caseGuesses estimates variations of capitalization
+func caseGuesses(token []rune) float64 {
+	var upper, lower int
+	for _, r := range token {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+	switch {
+	case upper == 0:
+		return 1
+	case lower == 0, upper == 1 && unicode.IsUpper(token[0]), upper == 1 && unicode.IsUpper(token[len(token)-1]):
+		return 2
+	}
+	return math.Pow(2, float64(upper))
+}
+
+func dictionaryMatches(pass []rune, user map[string]int) []Match {
+	var matches []Match
+	lower := []rune(strings.ToLower(string(pass)))
+	for i := range pass {
+		for j := i + 1; j <= len(pass); j++ {
+			token := lower[i:j]
+			var best *Match
+			try := func(p Pattern, word string, dict map[string]int, mult float64, leet bool) {
+				r, ok := dict[word]
+				if !ok {
+					return
+				}
+				g := math.Max(float64(r)*mult*caseGuesses(pass[i:j]), minGuesses)
+				if best == nil || g < best.Guesses {
+					best = &Match{Pattern: p, Token: string(pass[i:j]), I: i, J: j, Guesses: g, L33t: leet}
+				}
+			}
+			plain := string(token)
+			rev := reverse(plain)
+			for _, d := range []struct {
+				p    Pattern
+				dict map[string]int
+			}{{Dictionary, dictionary}, {UserInput, user}} {
+				try(d.p, plain, d.dict, 1, false)
+				try(d.p, rev, d.dict, 2, false)
+				for _, v := range unleet(token) {
+					if v != plain {
+						try(d.p, v, d.dict, 2, true)
+					}
+				}
+			}
+			if best != nil {
+				matches = append(matches, *best)
+			}
+		}
+	}
+	return matches
+}
+
+func reverse(s string) string {
+	r := []rune(s)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+	return string(r)
+}
+
+// keyboard rows and columns, unshifted and shifted
+var keyboardLines = []string{
+	"`1234567890-=", "qwertyuiop[]\\", "asdfghjkl;'", "zxcvbnm,./",
+	"~!@#$%^&*()_+", "qwertyuiop{}|", "asdfghjkl:\"", "zxcvbnm<>?",
+	"qwertzuiopü+", "asdfghjklöä#", "yxcvbnm,.-", "!\"§$%&/()=?",
+	"1qaz", "2wsx", "3edc", "4rfv", "5tgb", "6yhn", "7ujm", "8ik,", "9ol.", "0p;/",
+	"!qaz", "@wsx", "#edc", "$rfv", "%tgb", "^yhn", "&ujm", "*ik<", "(ol>", ")p:?",
+	"1qay", "2wsx", "3edc", "4rfv", "5tgb", "6zhn", "7ujm", "8ik,", "9ol.", "0pö-",
+	"789", "456", "123", "741", "852", "963", "147", "258", "369",
+}
+
+// keyboardMatches finds walks of at least 3 keys along a line
+func keyboardMatches(pass []rune) []Match {
+	var matches []Match
+	lower := []rune(strings.ToLower(string(pass)))
+	for i := range lower {
+		best := i + 1
+		for _, line := range keyboardLines {
+			for _, l := range []string{line, reverse(line)} {
+				lr := []rune(l)
+				for k := range lr {
+					n := 0
+					for i+n < len(lower) && k+n < len(lr) && lower[i+n] == lr[k+n] {
+						n++
+					}
+					if i+n > best {
+						best = i + n
+					}
+				}
+			}
+		}
+		if n := best - i; n >= 3 {
+			// start key, direction and length
+			g := float64(len(keyboardLines)) * 2 * float64(n) * caseGuesses(pass[i:best])
+			matches = append(matches, Match{Pattern: Keyboard, Token: string(pass[i:best]), I: i, J: best, Guesses: g})
+		}
+	}
+	return matches
+}
+
+// reference year of date guesses
+const refYear = 2026
+
+func yearGuesses(y int) float64 {
+	return math.Max(math.Abs(float64(y-refYear)), 20)
+}
+
+func validDate(d, m, y int) bool {
+	return d >= 1 && d <= 31 && m >= 1 && m <= 12 && y >= 1900 && y <= 2099
+}
+
+func fullYear(y int) int {
+	switch {
+	case y >= 100:
+		return y
+	case y > refYear%100:
+		return 1900 + y
+	}
+	return 2000 + y
+}
+
+// dateMatches finds years and day, month, year combinations
+func dateMatches(pass []rune) []Match {
+	var matches []Match
+	for i := range pass {
+		for j := i + 4; j <= len(pass) && j <= i+10; j++ {
+			token := string(pass[i:j])
+			if g, ok := dateGuesses(token); ok {
+				matches = append(matches, Match{Pattern: Date, Token: token, I: i, J: j, Guesses: g})
+			}
+		}
+	}
+	return matches
+}
+
+func dateGuesses(token string) (float64, bool) {
+	sep := 1.0
+	digits := token
+	for _, s := range []string{".", "-", "/", "_", " "} {
+		if parts := strings.Split(token, s); len(parts) == 3 {
+			digits = strings.Join(parts, "")
+			sep = 4
+			break
+		}
+	}
+	if _, err := strconv.Atoi(digits); err != nil || digits[0] == '-' || digits[0] == '+' {
+		return 0, false
+	}
+	num := func(s string) int {
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	switch len(digits) {
+	case 4:
+		if y := num(digits); sep == 1 && y >= 1900 && y <= 2099 {
+			return yearGuesses(y), true
+		}
+		// dmyy, mdyy
+		if y := fullYear(num(digits[2:])); validDate(num(digits[:1]), num(digits[1:2]), y) {
+			return yearGuesses(y) * 365 * sep, true
+		}
+	case 6:
+		// ddmmyy, mmddyy, yymmdd
+		if y := fullYear(num(digits[4:])); validDate(num(digits[:2]), num(digits[2:4]), y) || validDate(num(digits[2:4]), num(digits[:2]), y) {
+			return yearGuesses(y) * 365 * sep, true
+		}
+		if y := fullYear(num(digits[:2])); validDate(num(digits[4:]), num(digits[2:4]), y) {
+			return yearGuesses(y) * 365 * sep, true
+		}
+	case 8:
+		// ddmmyyyy, mmddyyyy, yyyymmdd
+		if y := num(digits[4:]); validDate(num(digits[:2]), num(digits[2:4]), y) || validDate(num(digits[2:4]), num(digits[:2]), y) {
+			return yearGuesses(y) * 365 * sep, true
+		}
+		if y := num(digits[:4]); validDate(num(digits[6:]), num(digits[4:6]), y) {
+			return yearGuesses(y) * 365 * sep, true
+		}
+	}
+	return 0, false
+}
+
+// repeatMatches finds tokens made of a repeated base
+func repeatMatches(pass []rune, user map[string]int) []Match {
+	var matches []Match
+	for i := range pass {
+		for size := 1; i+2*size <= len(pass); size++ {
+			base := pass[i : i+size]
+			j := i + size
+			for j+size <= len(pass) && string(pass[j:j+size]) == string(base) {
+				j += size
+			}
+			count := (j - i) / size
+			if count < 2 || (size == 1 && count < 3) {
+				continue
+			}
+			g := estimate(base, user).Guesses * float64(count)
+			matches = append(matches, Match{Pattern: Repeat, Token: string(pass[i:j]), I: i, J: j, Guesses: g})
+		}
+	}
+	return matches
+}
+
+// sequenceMatches finds runs of at least 3 characters with constant step
+func sequenceMatches(pass []rune) []Match {
+	var matches []Match
+	for i := 0; i+2 < len(pass); {
+		delta := pass[i+1] - pass[i]
+		if delta != 1 && delta != -1 || class(pass[i]) != class(pass[i+1]) {
+			i++
+			continue
+		}
+		j := i + 2
+		for j < len(pass) && pass[j]-pass[j-1] == delta && class(pass[j]) == class(pass[i]) {
+			j++
+		}
+		if j-i < 3 {
+			i++
+			continue
+		}
+		var g float64
+		switch first := unicode.ToLower(pass[i]); {
+		case strings.ContainsRune("aAzZ019", first):
+			g = 4
+		case unicode.IsDigit(first):
+			g = 10
+		default:
+			g = 26
+		}
+		g *= float64(j - i)
+		if delta < 0 {
+			g *= 2
+		}
+		matches = append(matches, Match{Pattern: Sequence, Token: string(pass[i:j]), I: i, J: j, Guesses: g})
+		i = j
+	}
+	return matches
+}
+
+// class of character and its bruteforce cardinality
+func class(r rune) int {
+	switch {
+	case unicode.IsDigit(r):
+		return 10
+	case unicode.IsLower(r), unicode.IsUpper(r):
+		return 26
+	case r < 0x80:
+		return 33
+	}
+	return 100
+}
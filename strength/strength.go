@@ -0,0 +1,129 @@
+// Package strength estimates password strength
+//
+// Passwords are decomposed into the cheapest sequence of patterns an
+// attacker would try: common passwords and words (also reversed, capitalized
+// or with l33t substitutions), keyboard walks, dates, repetitions and
+// sequences. Remaining characters are guessed by brute force. The number of
+// guesses of the decomposition is the product of its matches.
+//
+// References:
+//   - https://www.usenix.org/conference/usenixsecurity16/technical-sessions/presentation/wheeler
+package strength
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Result of estimation
+type Result struct {
+	Guesses float64 // estimated guesses
+	Entropy float64 // log2 of guesses
+	Score   int     // 0 (too guessable) to 4 (very unguessable)
+	Matches []Match // cheapest decomposition
+}
+
+// MaxLength of estimated input, longer passwords are estimated by their
+// prefix as estimation time grows faster than length
+const MaxLength = 40
+
+// score thresholds, guesses below are rated lower
+var thresholds = []float64{1e3, 1e6, 1e8, 1e10}
+
+// Estimate password strength, user inputs such as user name or company are
+// treated as dictionary words
+func Estimate(pass string, user ...string) Result {
+	inputs := make(map[string]int)
+	for i, u := range user {
+		if u = strings.ToLower(u); u != "" {
+			inputs[u] = i + 1
+		}
+	}
+	runes := []rune(pass)
+	if len(runes) > MaxLength {
+		runes = runes[:MaxLength]
+	}
+	r := estimate(runes, inputs)
+	for _, t := range thresholds {
+		if r.Guesses >= t {
+			r.Score++
+		}
+	}
+	return r
+}
+
+func estimate(pass []rune, user map[string]int) Result {
+	var matches []Match
+	matches = append(matches, dictionaryMatches(pass, user)...)
+	matches = append(matches, keyboardMatches(pass)...)
+	matches = append(matches, dateMatches(pass)...)
+	matches = append(matches, sequenceMatches(pass)...)
+	if len(pass) <= MaxRepeat {
+		matches = append(matches, repeatMatches(pass, user)...)
+	}
+	byEnd := make([][]Match, len(pass)+1)
+	for _, m := range matches {
+		byEnd[m.J] = append(byEnd[m.J], m)
+	}
+
+	// cost[j] is log2 guesses of cheapest decomposition of pass[:j]
+	cost := make([]float64, len(pass)+1)
+	prev := make([]Match, len(pass)+1)
+	for j := 1; j <= len(pass); j++ {
+		card := float64(class(pass[j-1]))
+		cost[j] = cost[j-1] + math.Log2(card)
+		prev[j] = Match{Pattern: Bruteforce, Token: string(pass[j-1 : j]), I: j - 1, J: j, Guesses: card}
+		for _, m := range byEnd[j] {
+			if c := cost[m.I] + math.Log2(m.Guesses); c < cost[j] {
+				cost[j], prev[j] = c, m
+			}
+		}
+	}
+
+	var r Result
+	for j := len(pass); j > 0; j = prev[j].I {
+		m := prev[j]
+		// merge adjacent bruteforce characters
+		if n := len(r.Matches); n > 0 && m.Pattern == Bruteforce && r.Matches[0].Pattern == Bruteforce {
+			b := &r.Matches[0]
+			b.Token, b.I, b.Guesses = m.Token+b.Token, m.I, m.Guesses*b.Guesses
+			continue
+		}
+		r.Matches = append([]Match{m}, r.Matches...)
+	}
+	r.Entropy = cost[len(pass)]
+	r.Guesses = math.Pow(2, r.Entropy)
+	return r
+}
+
+// MaxRepeat is longest password searched for repetitions
+const MaxRepeat = 40
+
+// Warnings explain weaknesses found
+func (r Result) Warnings() []string {
+	var w []string
+	for _, m := range r.Matches {
+		switch m.Pattern {
+		case Dictionary:
+			w = append(w, fmt.Sprintf("%q is a common password or word", m.Token))
+		case UserInput:
+			w = append(w, fmt.Sprintf("%q is derived from user data", m.Token))
+		case Keyboard:
+			w = append(w, fmt.Sprintf("%q is a keyboard pattern", m.Token))
+		case Date:
+			w = append(w, fmt.Sprintf("%q is a date or year", m.Token))
+		case Repeat:
+			w = append(w, fmt.Sprintf("%q is a repetition", m.Token))
+		case Sequence:
+			w = append(w, fmt.Sprintf("%q is a sequence", m.Token))
+		}
+		if m.L33t {
+			w = append(w, fmt.Sprintf("substitutions in %q are predictable", m.Token))
+		}
+	}
+	if r.Score < len(thresholds) {
+		w = append(w, fmt.Sprintf("about %.0f guesses needed, add more unpredictable characters", r.Guesses))
+	}
+	return w
+}
@@ -0,0 +1,71 @@
+package strength
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEstimate(t *testing.T) {
+	testCases := []struct {
+		pass    string
+		user    []string
+		score   int
+		pattern Pattern // of first match
+	}{
+		{pass: `Password1!`, score: 1, pattern: Dictionary},
+		{pass: `P@ssw0rd`, score: 0, pattern: Dictionary},
+		{pass: `drowssap`, score: 0, pattern: Dictionary},
+		{pass: `qwertz123`, score: 1, pattern: Dictionary},
+		{pass: `zxcvbnm,.`, score: 0, pattern: Keyboard},
+		{pass: `1qaz2wsx3edc`, score: 1, pattern: Dictionary},
+		{pass: `3edc4rfv`, score: 1, pattern: Keyboard},
+		{pass: `06.07.1992`, score: 1, pattern: Date},
+		{pass: `Sommer2024!`, score: 1, pattern: Dictionary},
+		{pass: `aaaaaaaaaaaa`, score: 0, pattern: Repeat},
+		{pass: `abcabcabc`, score: 0, pattern: Repeat},
+		{pass: `abcdefgh`, score: 0, pattern: Sequence},
+		{pass: `Firefighter01`, user: []string{"FIREFIGHTER"}, score: 1, pattern: UserInput},
+		{pass: `hX4#qv9!Lm2p`, score: 4, pattern: Bruteforce},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.pass, func(t *testing.T) {
+			r := Estimate(tc.pass, tc.user...)
+			if r.Score != tc.score {
+				t.Errorf("score: got %d, want %d (%+v)", r.Score, tc.score, r)
+			}
+			if len(r.Matches) == 0 || r.Matches[0].Pattern != tc.pattern {
+				t.Errorf("pattern: got %+v, want %v", r.Matches, tc.pattern)
+			}
+			var n int
+			for _, m := range r.Matches {
+				n += m.J - m.I
+			}
+			if n != len([]rune(tc.pass)) {
+				t.Errorf("matches cover %d of %d characters", n, len(tc.pass))
+			}
+		})
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	if w := Estimate(`Password1!`).Warnings(); len(w) == 0 {
+		t.Error("no warnings")
+	}
+	if w := Estimate(`hX4#qv9!Lm2pZ8`).Warnings(); len(w) != 0 {
+		t.Errorf("got %v", w)
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	r := Estimate(``)
+	if r.Score != 0 || r.Guesses != 1 || len(r.Matches) != 0 {
+		t.Errorf("got %+v", r)
+	}
+}
+
+func TestLong(t *testing.T) {
+	r := Estimate(strings.Repeat(`hX4#qv9!Lm2pZ8`, 300))
+	if j := r.Matches[len(r.Matches)-1].J; j != MaxLength {
+		t.Errorf("got estimation up to %d, want %d", j, MaxLength)
+	}
+}
@@ -0,0 +1,262 @@
+// Package synth generates synthetic USR02 dumps for load and regression
+// testing
+//
+// No real hashes are involved. Users with known weak passwords get genuine
+// CODVN H hashes, without legacy columns, and are listed in the answer key,
+// all other hashes and legacy columns are random bytes of the right size,
+// indistinguishable from hashes of unknown passwords. The same seed and
+// reference date produce the same dump.
+package synth
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/dim13/codvn"
+	"github.com/dim13/codvn/audit"
+	"github.com/dim13/codvn/usr02"
+)
+
+// Config of generator
+type Config struct {
+	Seed    int64     // random seed
+	Users   int       // users per client, besides standard users
+	Clients []string  // clients, 000 and 001 if empty
+	Weak    float64   // fraction of users with known weak password
+	MaxIter int       // caps iterations of weak hashes, if set
+	Now     time.Time // reference date, Epoch if zero
+}
+
+// Epoch is the default reference date, fixed so dumps are reproducible
+var Epoch = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Answer for user with known password
+type Answer struct {
+	Client   string
+	User     string
+	Password string
+}
+
+// Weak passwords of answer key
+var Weak = []string{
+	"Welcome1", "Password1!", "Sommer2024", "Winter2025!", "Initial1",
+	"Start123", "Passwort1", "Abcd1234", "Qwertz123", "Hallo123!",
+}
+
+// standard users of audit.DefaultPasswords, sorted so dumps are
+// reproducible
+func standardUsers() []string {
+	var users []string
+	for u := range audit.DefaultPasswords {
+		users = append(users, u)
+	}
+	sort.Strings(users)
+	return users
+}
+
+var hashSize = map[codvn.Kind]int{
+	codvn.SHA1:   20,
+	codvn.SHA256: 32,
+	codvn.SHA384: 48,
+	codvn.SHA512: 64,
+}
+
+type scheme struct {
+	kind codvn.Kind
+	iter int
+	salt int
+}
+
+// hash schemes found in the wild, by share
+var schemes = []struct {
+	share float64
+	scheme
+}{
+	{0.15, scheme{codvn.SHA1, 1024, 12}},
+	{0.05, scheme{codvn.SHA256, 10000, 16}},
+	{0.05, scheme{codvn.SHA384, 7500, 12}},
+	{0.75, scheme{codvn.SHA512, 15000, 16}},
+}
+
+// code versions by share
+var versions = []struct {
+	share float64
+	codvn string
+}{
+	{0.55, usr02.CodvnH},
+	{0.30, usr02.CodvnI},
+	{0.05, usr02.CodvnG},
+	{0.05, usr02.CodvnF},
+	{0.05, usr02.CodvnB},
+}
+
+// user types by share
+var types = []struct {
+	share float64
+	typ   string
+}{
+	{0.80, usr02.Dialog},
+	{0.08, usr02.System},
+	{0.05, usr02.Communication},
+	{0.04, usr02.Service},
+	{0.03, usr02.Reference},
+}
+
+// Generator of records
+type Generator struct {
+	Config
+	rnd *rand.Rand
+}
+
+// New generator
+func New(c Config) *Generator {
+	if len(c.Clients) == 0 {
+		c.Clients = []string{"000", "001"}
+	}
+	if c.Now.IsZero() {
+		c.Now = Epoch
+	}
+	c.Now = time.Date(c.Now.Year(), c.Now.Month(), c.Now.Day(), 0, 0, 0, 0, time.UTC)
+	return &Generator{Config: c, rnd: rand.New(rand.NewSource(c.Seed))}
+}
+
+func (g *Generator) bytes(n int) []byte {
+	b := make([]byte, n)
+	g.rnd.Read(b)
+	return b
+}
+
+func (g *Generator) choose(shares []float64) int {
+	x := g.rnd.Float64()
+	for i, s := range shares {
+		if x < s {
+			return i
+		}
+		x -= s
+	}
+	return len(shares) - 1
+}
+
+func (g *Generator) date(maxDays int) time.Time {
+	return g.Now.AddDate(0, 0, -g.rnd.Intn(maxDays))
+}
+
+// record with random or, if pass is given, genuine hash
+func (g *Generator) record(client, user, typ, pass string) (usr02.Record, error) {
+	r := usr02.Record{Client: client, User: user, Type: typ}
+	if pass != "" {
+		// known passwords need PWDSALTEDHASH to be verifiable, legacy
+		// columns would be random and not match the answer key
+		r.Codvn = usr02.CodvnH
+	} else {
+		var shares []float64
+		for _, v := range versions {
+			shares = append(shares, v.share)
+		}
+		r.Codvn = versions[g.choose(shares)].codvn
+	}
+	switch r.Codvn {
+	case usr02.CodvnB:
+		r.Bcode = g.bytes(usr02.BcodeSize)
+	case usr02.CodvnF:
+		r.Passcode = g.bytes(usr02.PasscodeSize)
+	case usr02.CodvnG, usr02.CodvnI:
+		r.Bcode = g.bytes(usr02.BcodeSize)
+		r.Passcode = g.bytes(usr02.PasscodeSize)
+	}
+	if r.Codvn == usr02.CodvnH || r.Codvn == usr02.CodvnI {
+		var shares []float64
+		for _, s := range schemes {
+			shares = append(shares, s.share)
+		}
+		s := schemes[g.choose(shares)].scheme
+		salt := g.bytes(s.salt)
+		if pass != "" {
+			iter := s.iter
+			if g.MaxIter > 0 && iter > g.MaxIter {
+				iter = g.MaxIter
+			}
+			h, err := codvn.New(s.kind, []byte(pass), salt, iter)
+			if err != nil {
+				return r, err
+			}
+			r.PwdSaltedHash = h
+		} else {
+			r.PwdSaltedHash = codvn.CodvN{Kind: s.kind, Iter: s.iter, Hash: g.bytes(hashSize[s.kind]), Salt: salt}
+		}
+	}
+	switch x := g.rnd.Float64(); {
+	case x < 0.03:
+		r.Lock = usr02.LockLogon
+		r.Failed = 5 + g.rnd.Intn(6)
+	case x < 0.08:
+		r.Lock = usr02.LockAdmin
+	case x < 0.09:
+		r.Lock = usr02.LockGlobal
+	default:
+		r.Failed = g.rnd.Intn(3)
+	}
+	if g.rnd.Float64() < 0.1 {
+		r.PwdState = usr02.PwdInitial
+	}
+	r.PwdChanged = g.date(5 * 365)
+	if typ == usr02.Dialog && g.rnd.Float64() < 0.9 {
+		r.LastLogon = g.date(3 * 365)
+		if r.LastLogon.Before(r.PwdChanged) {
+			r.LastLogon = r.PwdChanged
+		}
+	}
+	return r, nil
+}
+
+// Generate records per client, calling emit for each, and returns answer
+// key of users with known passwords
+func (g *Generator) Generate(emit func(usr02.Record) error) ([]Answer, error) {
+	var key []Answer
+	var shares []float64
+	for _, t := range types {
+		shares = append(shares, t.share)
+	}
+	for _, client := range g.Clients {
+		for _, user := range standardUsers() {
+			pass := ""
+			if g.rnd.Float64() < 0.2 {
+				pass = audit.DefaultPasswords[user][0]
+			}
+			r, err := g.record(client, user, usr02.System, pass)
+			if err != nil {
+				return key, err
+			}
+			if err := emit(r); err != nil {
+				return key, err
+			}
+			if pass != "" {
+				key = append(key, Answer{Client: client, User: user, Password: pass})
+			}
+		}
+		for i := 0; i < g.Users; i++ {
+			typ := types[g.choose(shares)].typ
+			user := fmt.Sprintf("U%06d", i)
+			if typ != usr02.Dialog {
+				user = fmt.Sprintf("T%s%05d", typ, i)
+			}
+			pass := ""
+			if g.rnd.Float64() < g.Weak {
+				pass = Weak[g.rnd.Intn(len(Weak))]
+			}
+			r, err := g.record(client, user, typ, pass)
+			if err != nil {
+				return key, err
+			}
+			if err := emit(r); err != nil {
+				return key, err
+			}
+			if pass != "" {
+				key = append(key, Answer{Client: client, User: user, Password: pass})
+			}
+		}
+	}
+	return key, nil
+}
@@ -0,0 +1,106 @@
+package synth
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dim13/codvn/audit"
+	"github.com/dim13/codvn/usr02"
+)
+
+func generate(t *testing.T, c Config) ([]usr02.Record, []Answer) {
+	var recs []usr02.Record
+	key, err := New(c).Generate(func(r usr02.Record) error {
+		recs = append(recs, r)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return recs, key
+}
+
+func TestGenerate(t *testing.T) {
+	c := Config{
+		Seed:    1,
+		Users:   500,
+		Clients: []string{"000", "100"},
+		Weak:    0.1,
+		MaxIter: 10,
+		Now:     time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	recs, key := generate(t, c)
+	if n := len(recs); n != 2*(500+len(audit.DefaultPasswords)) {
+		t.Errorf("got %d records", n)
+	}
+	if len(key) < 50 || len(key) > 200 {
+		t.Errorf("got %d answers", len(key))
+	}
+	codvns := make(map[string]int)
+	for _, r := range recs {
+		codvns[r.Codvn]++
+		if (r.Codvn == usr02.CodvnH || r.Codvn == usr02.CodvnI) != r.HasHash() {
+			t.Errorf("%s: CODVN %s, hash %v", r.User, r.Codvn, r.PwdSaltedHash)
+		}
+	}
+	if len(codvns) != len(versions) {
+		t.Errorf("got code versions %v", codvns)
+	}
+
+	// answers verify against their records
+	byUser := make(map[string]usr02.Record)
+	for _, r := range recs {
+		byUser[r.Client+"/"+r.User] = r
+	}
+	for _, a := range key {
+		r := byUser[a.Client+"/"+a.User]
+		if r.Codvn != usr02.CodvnH || !r.Bcode.IsZero() || !r.Passcode.IsZero() {
+			t.Errorf("%s/%s: CODVN %s with legacy columns", a.Client, a.User, r.Codvn)
+		}
+		if err := r.PwdSaltedHash.Verify([]byte(a.Password)); err != nil {
+			t.Errorf("%s/%s: %v", a.Client, a.User, err)
+		}
+	}
+
+	// same seed, same dump
+	again, _ := generate(t, c)
+	if !reflect.DeepEqual(recs, again) {
+		t.Error("not reproducible")
+	}
+}
+
+func TestEpoch(t *testing.T) {
+	recs, _ := generate(t, Config{Seed: 3, Users: 50, MaxIter: 1})
+	for _, r := range recs {
+		if r.PwdChanged.After(Epoch) || r.LastLogon.After(Epoch) {
+			t.Fatalf("%s: dates %v %v after %v", r.User, r.PwdChanged, r.LastLogon, Epoch)
+		}
+	}
+}
+
+func TestFormats(t *testing.T) {
+	recs, _ := generate(t, Config{Seed: 2, Users: 50, MaxIter: 1})
+	for format, comma := range usr02.Formats {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := usr02.NewWriter(&buf, comma)
+			for _, r := range recs {
+				if err := w.Write(r); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			got, err := usr02.NewReader(&buf, comma).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, recs) {
+				t.Error("round trip differs")
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package tacacs
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"io"
+)
+
+// Versions
+const (
+	majorVersion   = 0xc
+	minorDefault   = 0x0
+	minorOne       = 0x1
+	headerLen      = 12
+	maxBodyLen     = 1 << 16
+	typeAuthen     = 0x01
+	typeAuthor     = 0x02
+	typeAcct       = 0x03
+	flagUnencrypt  = 0x01
+	flagSingleConn = 0x04
+)
+
+// Authentication actions
+const (
+	actionLogin = 0x01
+)
+
+// Authentication types
+const (
+	authenASCII = 0x01
+	authenPAP   = 0x02
+)
+
+// Reply status
+const (
+	statusPass    = 0x01
+	statusFail    = 0x02
+	statusGetUser = 0x04
+	statusGetPass = 0x05
+	statusError   = 0x07
+	authorError   = 0x11
+	acctError     = 0x02
+)
+
+// Reply and continue flags
+const (
+	replyNoEcho   = 0x01
+	continueAbort = 0x01
+)
+
+type header struct {
+	version byte
+	typ     byte
+	seq     byte
+	flags   byte
+	session uint32
+	length  uint32
+}
+
+func (h header) marshal() []byte {
+	b := make([]byte, headerLen)
+	b[0], b[1], b[2], b[3] = h.version, h.typ, h.seq, h.flags
+	binary.BigEndian.PutUint32(b[4:], h.session)
+	binary.BigEndian.PutUint32(b[8:], h.length)
+	return b
+}
+
+func unmarshalHeader(b []byte) header {
+	return header{
+		version: b[0],
+		typ:     b[1],
+		seq:     b[2],
+		flags:   b[3],
+		session: binary.BigEndian.Uint32(b[4:]),
+		length:  binary.BigEndian.Uint32(b[8:]),
+	}
+}
+
+// obfuscate body in place with MD5 pseudo pad, the operation is its own
+// inverse (RFC 8907 section 4.5)
+func obfuscate(h header, key, body []byte) {
+	if len(key) == 0 {
+		return
+	}
+	var sess [4]byte
+	binary.BigEndian.PutUint32(sess[:], h.session)
+	var prev []byte
+	for i := 0; i < len(body); i += md5.Size {
+		m := md5.New()
+		m.Write(sess[:])
+		m.Write(key)
+		m.Write([]byte{h.version, h.seq})
+		m.Write(prev)
+		prev = m.Sum(nil)
+		for j := 0; j < md5.Size && i+j < len(body); j++ {
+			body[i+j] ^= prev[j]
+		}
+	}
+}
+
+type packet struct {
+	header
+	body []byte
+}
+
+func readPacket(r io.Reader, key []byte) (packet, error) {
+	b := make([]byte, headerLen)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return packet{}, err
+	}
+	h := unmarshalHeader(b)
+	if h.version>>4 != majorVersion {
+		return packet{}, ErrVersion
+	}
+	if h.length > maxBodyLen {
+		return packet{}, ErrMalformed
+	}
+	body := make([]byte, h.length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return packet{}, err
+	}
+	if h.flags&flagUnencrypt == 0 {
+		obfuscate(h, key, body)
+	} else if len(key) > 0 {
+		return packet{}, ErrUnencrypted
+	}
+	return packet{header: h, body: body}, nil
+}
+
+func writePacket(w io.Writer, key []byte, p packet) error {
+	p.length = uint32(len(p.body))
+	body := append([]byte(nil), p.body...)
+	if len(key) == 0 {
+		p.flags |= flagUnencrypt
+	} else {
+		p.flags &^= flagUnencrypt
+		obfuscate(p.header, key, body)
+	}
+	_, err := w.Write(append(p.marshal(), body...))
+	return err
+}
+
+// start body of authentication
+type start struct {
+	action, privLvl, authenType, service byte
+	user, port, remAddr, data            []byte
+}
+
+func (s *start) unmarshal(b []byte) error {
+	if len(b) < 8 {
+		return ErrMalformed
+	}
+	s.action, s.privLvl, s.authenType, s.service = b[0], b[1], b[2], b[3]
+	n := 8 + int(b[4]) + int(b[5]) + int(b[6]) + int(b[7])
+	if len(b) != n {
+		return ErrMalformed
+	}
+	rest := b[8:]
+	s.user, rest = rest[:b[4]], rest[b[4]:]
+	s.port, rest = rest[:b[5]], rest[b[5]:]
+	s.remAddr, rest = rest[:b[6]], rest[b[6]:]
+	s.data = rest[:b[7]]
+	return nil
+}
+
+func (s start) marshal() []byte {
+	b := []byte{s.action, s.privLvl, s.authenType, s.service,
+		byte(len(s.user)), byte(len(s.port)), byte(len(s.remAddr)), byte(len(s.data))}
+	b = append(b, s.user...)
+	b = append(b, s.port...)
+	b = append(b, s.remAddr...)
+	return append(b, s.data...)
+}
+
+// reply body of authentication
+type reply struct {
+	status, flags byte
+	msg, data     []byte
+}
+
+func (r reply) marshal() []byte {
+	b := make([]byte, 6)
+	b[0], b[1] = r.status, r.flags
+	binary.BigEndian.PutUint16(b[2:], uint16(len(r.msg)))
+	binary.BigEndian.PutUint16(b[4:], uint16(len(r.data)))
+	b = append(b, r.msg...)
+	return append(b, r.data...)
+}
+
+func (r *reply) unmarshal(b []byte) error {
+	if len(b) < 6 {
+		return ErrMalformed
+	}
+	r.status, r.flags = b[0], b[1]
+	m, d := int(binary.BigEndian.Uint16(b[2:])), int(binary.BigEndian.Uint16(b[4:]))
+	if len(b) != 6+m+d {
+		return ErrMalformed
+	}
+	r.msg, r.data = b[6:6+m], b[6+m:]
+	return nil
+}
+
+// continue body of authentication
+type cont struct {
+	msg, data []byte
+	flags     byte
+}
+
+func (c *cont) unmarshal(b []byte) error {
+	if len(b) < 5 {
+		return ErrMalformed
+	}
+	m, d := int(binary.BigEndian.Uint16(b)), int(binary.BigEndian.Uint16(b[2:]))
+	if len(b) != 5+m+d {
+		return ErrMalformed
+	}
+	c.flags = b[4]
+	c.msg, c.data = b[5:5+m], b[5+m:]
+	return nil
+}
+
+func (c cont) marshal() []byte {
+	b := make([]byte, 5)
+	binary.BigEndian.PutUint16(b, uint16(len(c.msg)))
+	binary.BigEndian.PutUint16(b[2:], uint16(len(c.data)))
+	b[4] = c.flags
+	b = append(b, c.msg...)
+	return append(b, c.data...)
+}
+
+// errorBody of reply with status ERROR to authorization or accounting
+// request, nil for unknown packet types
+func errorBody(typ byte, msg []byte) []byte {
+	var b []byte
+	switch typ {
+	case typeAuthor:
+		b = make([]byte, 6)
+		b[0] = authorError
+		binary.BigEndian.PutUint16(b[2:], uint16(len(msg)))
+	case typeAcct:
+		b = make([]byte, 5)
+		binary.BigEndian.PutUint16(b[0:], uint16(len(msg)))
+		b[4] = acctError
+	default:
+		return nil
+	}
+	return append(b, msg...)
+}
@@ -0,0 +1,204 @@
+// Package tacacs implements TACACS+ authentication server verifying
+// credentials against CODVN H hashes
+//
+// ASCII login (prompting for user name and password) and PAP login are
+// supported, bodies are obfuscated with the shared key. Authorization and
+// accounting are not implemented, requests get status ERROR.
+//
+// References:
+//   - https://tools.ietf.org/html/rfc8907
+package tacacs
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net"
+	"time"
+
+	"github.com/dim13/codvn/store"
+)
+
+// Errors
+var (
+	ErrVersion     = errors.New("unsupported version")
+	ErrMalformed   = errors.New("malformed packet")
+	ErrUnencrypted = errors.New("unencrypted packet with key configured")
+	ErrSequence    = errors.New("unexpected sequence number")
+)
+
+// Server of TACACS+ authentication
+type Server struct {
+	Key      []byte             // shared secret, unencrypted packets are rejected if set
+	Verifier store.UserVerifier // verifies credentials
+	Timeout  time.Duration      // read deadline per packet, none if zero
+	ErrorLog *log.Logger
+}
+
+// Default prompts
+const (
+	UserPrompt = "Username: "
+	PassPrompt = "Password: "
+)
+
+// Serve connections of listener
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.ServeConn(conn)
+	}
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.ErrorLog != nil {
+		s.ErrorLog.Printf(format, args...)
+	}
+}
+
+type state int
+
+const (
+	getUser state = iota + 1
+	getPass
+)
+
+type session struct {
+	state state
+	user  string
+	seq   byte // last sequence number sent
+}
+
+// ServeConn handles sessions of connection until closed, with single
+// connection mode multiple sessions share it
+func (s *Server) ServeConn(conn net.Conn) {
+	defer conn.Close()
+	sessions := make(map[uint32]*session)
+	single := false
+	for {
+		if s.Timeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(s.Timeout))
+		}
+		p, err := readPacket(conn, s.Key)
+		if err != nil {
+			if err != io.EOF {
+				s.logf("tacacs: %v: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		if p.typ != typeAuthen {
+			s.logf("tacacs: %v: packet type %d not supported", conn.RemoteAddr(), p.typ)
+			body := errorBody(p.typ, []byte("not supported"))
+			if body == nil {
+				return
+			}
+			single = single || p.flags&flagSingleConn != 0
+			if _, err := s.reply(conn, p, body, single); err != nil || !single {
+				return
+			}
+			continue
+		}
+		sess, ok := sessions[p.session]
+		if !ok {
+			if p.seq != 1 {
+				s.logf("tacacs: %v: %v", conn.RemoteAddr(), ErrSequence)
+				return
+			}
+			sess = &session{}
+			sessions[p.session] = sess
+			single = single || p.flags&flagSingleConn != 0
+		}
+		r, done := s.handle(sess, p)
+		if r != nil {
+			if sess.seq, err = s.reply(conn, p, r.marshal(), single); err != nil {
+				return
+			}
+		}
+		if done {
+			delete(sessions, p.session)
+			if !single {
+				return
+			}
+		}
+	}
+}
+
+// reply to packet with body, returns sequence number sent
+func (s *Server) reply(conn net.Conn, p packet, body []byte, single bool) (byte, error) {
+	out := packet{header: p.header, body: body}
+	out.seq = p.seq + 1
+	out.flags = 0
+	if single {
+		out.flags |= flagSingleConn
+	}
+	if err := writePacket(conn, s.Key, out); err != nil {
+		s.logf("tacacs: %v: %v", conn.RemoteAddr(), err)
+		return 0, err
+	}
+	return out.seq, nil
+}
+
+// handle packet of session, returns reply, if any, and whether session ends
+func (s *Server) handle(sess *session, p packet) (*reply, bool) {
+	if sess.state == 0 {
+		var st start
+		if err := st.unmarshal(p.body); err != nil {
+			return &reply{status: statusError, msg: []byte(err.Error())}, true
+		}
+		return s.start(sess, p, st)
+	}
+	if p.seq != sess.seq+1 {
+		return &reply{status: statusError, msg: []byte(ErrSequence.Error())}, true
+	}
+	var c cont
+	if err := c.unmarshal(p.body); err != nil {
+		return &reply{status: statusError, msg: []byte(err.Error())}, true
+	}
+	if c.flags&continueAbort != 0 {
+		return nil, true
+	}
+	switch sess.state {
+	case getUser:
+		if len(c.msg) == 0 {
+			return &reply{status: statusGetUser, msg: []byte(UserPrompt)}, false
+		}
+		sess.user = string(c.msg)
+		sess.state = getPass
+		return &reply{status: statusGetPass, flags: replyNoEcho, msg: []byte(PassPrompt)}, false
+	case getPass:
+		return s.verify(sess.user, c.msg), true
+	}
+	return &reply{status: statusError}, true
+}
+
+func (s *Server) start(sess *session, p packet, st start) (*reply, bool) {
+	if st.action != actionLogin {
+		return &reply{status: statusFail, msg: []byte("action not supported")}, true
+	}
+	switch st.authenType {
+	case authenASCII:
+		if len(st.user) == 0 {
+			sess.state = getUser
+			return &reply{status: statusGetUser, msg: []byte(UserPrompt)}, false
+		}
+		sess.user = string(st.user)
+		sess.state = getPass
+		return &reply{status: statusGetPass, flags: replyNoEcho, msg: []byte(PassPrompt)}, false
+	case authenPAP:
+		if p.version&0xf != minorOne || len(st.user) == 0 {
+			return &reply{status: statusError, msg: []byte(ErrVersion.Error())}, true
+		}
+		return s.verify(string(st.user), st.data), true
+	}
+	return &reply{status: statusFail, msg: []byte("authentication type not supported")}, true
+}
+
+func (s *Server) verify(user string, pass []byte) *reply {
+	if err := s.Verifier.Verify(user, pass); err != nil {
+		s.logf("tacacs: %s: %v", user, err)
+		return &reply{status: statusFail, msg: []byte("Authentication failed")}
+	}
+	return &reply{status: statusPass}
+}
@@ -0,0 +1,241 @@
+package tacacs
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dim13/codvn"
+	"github.com/dim13/codvn/store"
+)
+
+var testKey = []byte("tac_plus_key")
+
+func listen(t *testing.T) net.Addr {
+	st := &store.Store{}
+	h, err := codvn.New(codvn.SHA512, []byte("Pindakaas!"), []byte("0123456789abcdef"), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Put("100", "ADMIN", h); err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{Key: testKey, Verifier: store.Verifier(st, "100")}
+	go s.Serve(l)
+	return l.Addr()
+}
+
+type client struct {
+	t       *testing.T
+	conn    net.Conn
+	key     []byte
+	version byte
+	session uint32
+	seq     byte
+	flags   byte
+}
+
+func dial(t *testing.T, addr net.Addr, minor byte, session uint32) *client {
+	conn, err := net.Dial("tcp", addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &client{t: t, conn: conn, key: testKey, version: majorVersion<<4 | minor, session: session}
+}
+
+func (c *client) send(body []byte) {
+	c.seq++
+	h := header{version: c.version, typ: typeAuthen, seq: c.seq, flags: c.flags, session: c.session}
+	if err := writePacket(c.conn, c.key, packet{header: h, body: body}); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *client) recv() reply {
+	p, err := readPacket(c.conn, c.key)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if p.seq != c.seq+1 || p.session != c.session {
+		c.t.Fatalf("got seq %d session %x", p.seq, p.session)
+	}
+	c.seq = p.seq
+	var r reply
+	if err := r.unmarshal(p.body); err != nil {
+		c.t.Fatal(err)
+	}
+	return r
+}
+
+func (c *client) expect(status byte) reply {
+	r := c.recv()
+	if r.status != status {
+		c.t.Fatalf("got status %d %q, want %d", r.status, r.msg, status)
+	}
+	return r
+}
+
+func (c *client) start(typ byte, user, data string) {
+	c.send(start{action: actionLogin, privLvl: 1, authenType: typ, service: 1,
+		user: []byte(user), port: []byte("tty0"), remAddr: []byte("192.0.2.1"), data: []byte(data)}.marshal())
+}
+
+func (c *client) cont(msg string) {
+	c.send(cont{msg: []byte(msg)}.marshal())
+}
+
+func TestASCII(t *testing.T) {
+	addr := listen(t)
+	testCases := []struct {
+		title  string
+		user   string // in start packet
+		prompt []string
+		status byte
+	}{
+		{title: "user given", user: "admin", prompt: []string{"Pindakaas!"}, status: statusPass},
+		{title: "prompt user", prompt: []string{"admin", "Pindakaas!"}, status: statusPass},
+		{title: "empty user", prompt: []string{"", "admin", "Pindakaas!"}, status: statusPass},
+		{title: "wrong", user: "admin", prompt: []string{"HashCat!"}, status: statusFail},
+		{title: "unknown", prompt: []string{"nobody", "Pindakaas!"}, status: statusFail},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			c := dial(t, addr, minorDefault, 0x1234)
+			defer c.conn.Close()
+			c.start(authenASCII, tc.user, "")
+			for i, p := range tc.prompt {
+				r := c.recv()
+				switch {
+				case r.status == statusGetPass && r.flags&replyNoEcho == 0:
+					t.Fatal("password echoed")
+				case r.status != statusGetUser && r.status != statusGetPass:
+					t.Fatalf("prompt %d: got status %d", i, r.status)
+				}
+				c.cont(p)
+			}
+			c.expect(tc.status)
+		})
+	}
+}
+
+func TestPAP(t *testing.T) {
+	addr := listen(t)
+	testCases := []struct {
+		title  string
+		minor  byte
+		pass   string
+		status byte
+	}{
+		{title: "pass", minor: minorOne, pass: "Pindakaas!", status: statusPass},
+		{title: "fail", minor: minorOne, pass: "HashCat!", status: statusFail},
+		{title: "version", minor: minorDefault, pass: "Pindakaas!", status: statusError},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			c := dial(t, addr, tc.minor, 0xdeadbeef)
+			defer c.conn.Close()
+			c.start(authenPAP, "admin", tc.pass)
+			c.expect(tc.status)
+		})
+	}
+}
+
+func TestAbort(t *testing.T) {
+	c := dial(t, listen(t), minorDefault, 1)
+	defer c.conn.Close()
+	c.start(authenASCII, "admin", "")
+	c.expect(statusGetPass)
+	c.send(cont{flags: continueAbort}.marshal())
+	// server closes connection without reply
+	if _, err := readPacket(c.conn, c.key); err == nil {
+		t.Error("got reply after abort")
+	}
+}
+
+func TestSingleConnect(t *testing.T) {
+	c := dial(t, listen(t), minorOne, 1)
+	defer c.conn.Close()
+	c.flags = flagSingleConn
+	for i, pass := range []string{"Pindakaas!", "HashCat!", "Pindakaas!"} {
+		c.session, c.seq = uint32(i+1), 0
+		c.start(authenPAP, "admin", pass)
+		want := byte(statusPass)
+		if i == 1 {
+			want = statusFail
+		}
+		c.expect(want)
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	c := dial(t, listen(t), minorDefault, 9)
+	defer c.conn.Close()
+	testCases := []struct {
+		typ  byte
+		want []byte // status ERROR at position of type
+	}{
+		{typ: typeAuthor, want: []byte{authorError, 0}},
+		{typ: typeAcct, want: []byte{0, 13, 0, 0, acctError}},
+	}
+	for _, tc := range testCases {
+		c.seq++
+		h := header{version: c.version, typ: tc.typ, seq: c.seq, flags: flagSingleConn, session: c.session}
+		if err := writePacket(c.conn, c.key, packet{header: h, body: []byte{0}}); err != nil {
+			t.Fatal(err)
+		}
+		p, err := readPacket(c.conn, c.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.typ != tc.typ || p.seq != c.seq+1 || !bytes.HasPrefix(p.body, tc.want) {
+			t.Errorf("got type %d seq %d body %x, want %d %d %x", p.typ, p.seq, p.body, tc.typ, c.seq+1, tc.want)
+		}
+		c.seq = p.seq
+	}
+	// connection still serves authentication
+	c.session, c.seq = 10, 0
+	c.start(authenASCII, "admin", "")
+	c.expect(statusGetPass)
+}
+
+func TestTimeout(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	s := &Server{Key: testKey, Timeout: 10 * time.Millisecond}
+	go s.ServeConn(server)
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := client.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("got %v, want %v", err, io.EOF)
+	}
+}
+
+func TestKey(t *testing.T) {
+	addr := listen(t)
+	c := dial(t, addr, minorOne, 7)
+	defer c.conn.Close()
+	c.key = nil
+	c.start(authenPAP, "admin", "Pindakaas!")
+	if _, err := readPacket(c.conn, c.key); err == nil {
+		t.Error("unencrypted packet accepted")
+	}
+}
+
+func TestObfuscate(t *testing.T) {
+	h := header{version: 0xc1, seq: 1, session: 0x01020304}
+	body := bytes.Repeat([]byte("secret body "), 5)
+	b := append([]byte(nil), body...)
+	obfuscate(h, testKey, b)
+	if bytes.Equal(b, body) {
+		t.Fatal("not obfuscated")
+	}
+	obfuscate(h, testKey, b)
+	if !bytes.Equal(b, body) {
+		t.Error("round trip differs")
+	}
+}
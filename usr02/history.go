@@ -0,0 +1,144 @@
+package usr02
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+	"time"
+)
+
+// History tables
+const (
+	TableUSH02      = "USH02"         // change documents
+	TablePwdHistory = "USRPWDHISTORY" // password history
+)
+
+// Entry of password history
+type Entry struct {
+	Record
+	Table     string    // source table
+	Changed   time.Time // MODDA and MODTI, or TIMESTAMP
+	ChangedBy string    // MODBE, USH02 only
+}
+
+// HasLegacy reports whether BCODE or PASSCODE is set
+func (e Entry) HasLegacy() bool {
+	return !e.Bcode.IsZero() || !e.Passcode.IsZero()
+}
+
+const (
+	timeLayout      = "150405"
+	timestampLayout = "20060102150405"
+)
+
+// HistoryReader reads USH02 and USRPWDHISTORY exports, exports with
+// TIMESTAMP column are taken for USRPWDHISTORY
+type HistoryReader struct {
+	r      *csv.Reader
+	header []string
+	table  string
+	line   int
+}
+
+// NewHistoryReader returns reader of export delimited by comma
+func NewHistoryReader(r io.Reader, comma rune) *HistoryReader {
+	return &HistoryReader{r: NewReader(r, comma).r}
+}
+
+func (r *HistoryReader) readHeader() error {
+	fields, err := r.r.Read()
+	if err == io.EOF {
+		return ErrHeader
+	}
+	if err != nil {
+		return err
+	}
+	r.line++
+	var client, user bool
+	r.header = make([]string, len(fields))
+	r.table = TableUSH02
+	for i, f := range fields {
+		name := strings.ToUpper(strings.TrimSpace(f))
+		r.header[i] = name
+		client = client || name == "MANDT"
+		user = user || name == "BNAME"
+		if name == "TIMESTAMP" {
+			r.table = TablePwdHistory
+		}
+	}
+	if !client || !user {
+		return ErrHeader
+	}
+	return nil
+}
+
+// set column of entry, clock collects MODTI
+func (e *Entry) set(name, value string, clock *time.Duration) error {
+	switch name {
+	case "MODDA":
+		d, err := parseDate(value)
+		e.Changed = d
+		return err
+	case "MODTI":
+		if value == "" {
+			return nil
+		}
+		t, err := time.Parse(timeLayout, value)
+		*clock = t.Sub(time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC))
+		return err
+	case "TIMESTAMP":
+		if value == "" || strings.Trim(value, "0") == "" {
+			return nil
+		}
+		t, err := time.Parse(timestampLayout, value)
+		e.Changed = t
+		return err
+	case "MODBE":
+		e.ChangedBy = value
+		return nil
+	}
+	return e.Record.Set(name, value)
+}
+
+// Read next entry, returns io.EOF at end of export
+func (r *HistoryReader) Read() (Entry, error) {
+	if r.header == nil {
+		if err := r.readHeader(); err != nil {
+			return Entry{}, err
+		}
+	}
+	fields, err := r.r.Read()
+	if err != nil {
+		return Entry{}, err
+	}
+	r.line++
+	e := Entry{Table: r.table}
+	var clock time.Duration
+	for i, f := range fields {
+		if i >= len(r.header) {
+			continue
+		}
+		if err := e.set(r.header[i], strings.TrimSpace(f), &clock); err != nil {
+			return Entry{}, &ParseError{Line: r.line, Column: r.header[i], Err: err}
+		}
+	}
+	if !e.Changed.IsZero() {
+		e.Changed = e.Changed.Add(clock)
+	}
+	return e, nil
+}
+
+// ReadAll remaining entries
+func (r *HistoryReader) ReadAll() ([]Entry, error) {
+	var entries []Entry
+	for {
+		e, err := r.Read()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, e)
+	}
+}
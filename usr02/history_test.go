@@ -0,0 +1,95 @@
+package usr02
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dim13/codvn"
+)
+
+func TestHistoryReader(t *testing.T) {
+	sha1, err := codvn.New(codvn.SHA1, []byte("Welcome1"), make([]byte, 12), 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sha512, err := codvn.New(codvn.SHA512, []byte("Welcome1"), make([]byte, 16), 15000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		name   string
+		export string
+		want   []Entry
+	}{
+		{
+			name: "USH02",
+			export: "MANDT\tBNAME\tMODDA\tMODTI\tMODBE\tUSTYP\tCODVN\tBCODE\tPASSCODE\tPWDSALTEDHASH\tTCODE\n" +
+				"001\tJDOE\t20240131\t123045\tADMIN\tA\tG\tA1B2C3D4E5F60718\t0102030405060708090A0B0C0D0E0F1011121314\t\tSU01\n" +
+				"001\tJDOE\t20250601\t080000\tJDOE\tA\tH\t\t\t" + sha1.String() + "\n",
+			want: []Entry{
+				{
+					Record:    Record{Client: "001", User: "JDOE", Type: Dialog, Codvn: CodvnG, Bcode: Bcode{0xa1, 0xb2, 0xc3, 0xd4, 0xe5, 0xf6, 0x07, 0x18}, Passcode: Passcode{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20}},
+					Table:     TableUSH02,
+					Changed:   time.Date(2024, 1, 31, 12, 30, 45, 0, time.UTC),
+					ChangedBy: "ADMIN",
+				},
+				{
+					Record:    Record{Client: "001", User: "JDOE", Type: Dialog, Codvn: CodvnH, PwdSaltedHash: sha1},
+					Table:     TableUSH02,
+					Changed:   time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC),
+					ChangedBy: "JDOE",
+				},
+			},
+		},
+		{
+			name: "USRPWDHISTORY",
+			export: "MANDT,BNAME,TIMESTAMP,PASSCODE,PWDSALTEDHASH\n" +
+				"000,DDIC,20231224180000,,\"" + sha512.String() + "\"\n" +
+				"000,DDIC,0,,\n",
+			want: []Entry{
+				{
+					Record:  Record{Client: "000", User: "DDIC", PwdSaltedHash: sha512},
+					Table:   TablePwdHistory,
+					Changed: time.Date(2023, 12, 24, 18, 0, 0, 0, time.UTC),
+				},
+				{Record: Record{Client: "000", User: "DDIC"}, Table: TablePwdHistory},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			comma := '\t'
+			if strings.Contains(tc.export[:6], ",") {
+				comma = ','
+			}
+			got, err := NewHistoryReader(strings.NewReader(tc.export), comma).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %d entries, want %d", len(got), len(tc.want))
+			}
+			for i, e := range got {
+				w := tc.want[i]
+				if e.Client != w.Client || e.User != w.User || e.Type != w.Type || e.Codvn != w.Codvn ||
+					e.Bcode.String() != w.Bcode.String() || e.Passcode.String() != w.Passcode.String() ||
+					e.PwdSaltedHash.Kind != w.PwdSaltedHash.Kind || e.PwdSaltedHash.Iter != w.PwdSaltedHash.Iter ||
+					e.Table != w.Table || !e.Changed.Equal(w.Changed) || e.ChangedBy != w.ChangedBy {
+					t.Errorf("got %+v, want %+v", e, w)
+				}
+				if e.HasLegacy() != w.HasLegacy() {
+					t.Errorf("got legacy %v, want %v", e.HasLegacy(), w.HasLegacy())
+				}
+			}
+		})
+	}
+}
+
+func TestHistoryParseError(t *testing.T) {
+	export := "MANDT\tBNAME\tMODDA\n000\tDDIC\t2024-01-31\n"
+	_, err := NewHistoryReader(strings.NewReader(export), '\t').Read()
+	if pe, ok := err.(*ParseError); !ok || pe.Line != 2 || pe.Column != "MODDA" {
+		t.Errorf("got %v, want parse error of MODDA in line 2", err)
+	}
+}
@@ -0,0 +1,109 @@
+package usr02
+
+import (
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+)
+
+// Bcode is CODVN B password hash (MD5 based, 8 bytes), kept as stored.
+// The algorithm itself is not implemented yet.
+type Bcode []byte
+
+// Passcode is CODVN F password hash (SHA-1 based, 20 bytes)
+type Passcode []byte
+
+// Sizes of legacy hashes
+const (
+	BcodeSize    = 8
+	PasscodeSize = 20
+)
+
+func legacyString(b []byte) string {
+	return strings.ToUpper(hex.EncodeToString(b))
+}
+
+// legacyParse decodes hex, all zeros mean no hash
+func legacyParse(text []byte, size int) ([]byte, error) {
+	if len(text) == 0 || strings.Trim(string(text), "0") == "" {
+		return nil, nil
+	}
+	b, err := hex.DecodeString(string(text))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != size {
+		return nil, ErrSize
+	}
+	return b, nil
+}
+
+// IsZero reports whether hash is absent
+func (b Bcode) IsZero() bool { return len(b) == 0 }
+
+func (b Bcode) String() string { return legacyString(b) }
+
+// MarshalText encodes hash as upper case hex
+func (b Bcode) MarshalText() ([]byte, error) { return []byte(b.String()), nil }
+
+// UnmarshalText decodes hex
+func (b *Bcode) UnmarshalText(text []byte) (err error) {
+	*b, err = legacyParse(text, BcodeSize)
+	return err
+}
+
+// IsZero reports whether hash is absent
+func (p Passcode) IsZero() bool { return len(p) == 0 }
+
+func (p Passcode) String() string { return legacyString(p) }
+
+// MarshalText encodes hash as upper case hex
+func (p Passcode) MarshalText() ([]byte, error) { return []byte(p.String()), nil }
+
+// UnmarshalText decodes hex
+func (p *Passcode) UnmarshalText(text []byte) (err error) {
+	*p, err = legacyParse(text, PasscodeSize)
+	return err
+}
+
+// passcodeMagic is mixed into PASSCODE, length and offset derive from
+// SHA-1 of password and user
+var passcodeMagic = [...]byte{
+	0x91, 0xac, 0x51, 0x14, 0x9f, 0x67, 0x54, 0x43, 0x24, 0xe7, 0x3b, 0xe0, 0x28, 0x74, 0x7b, 0xc2,
+	0x86, 0x33, 0x13, 0xeb, 0x5a, 0x4f, 0xcb, 0x5c, 0x08, 0x0a, 0x73, 0x37, 0x0e, 0x5d, 0x1c, 0x2f,
+	0x33, 0x8f, 0xe6, 0xe5, 0xf8, 0x9b, 0xae, 0xdd, 0x16, 0xf2, 0x4b, 0x8d, 0x2c, 0xe1, 0xd4, 0xdc,
+	0xb0, 0xcb, 0xdf, 0x9d, 0xd4, 0x70, 0x6d, 0x17, 0xf9, 0x4d, 0x42, 0x3f, 0x9b, 0x1b, 0x11, 0x94,
+	0x9f, 0x5b, 0xc1, 0x9b, 0x06, 0x05, 0x9d, 0x03, 0x9d, 0x5e, 0x13, 0x8a, 0x1e, 0x9a, 0x6a, 0xe8,
+	0xd9, 0x7c, 0x14, 0x17, 0x58, 0xc7, 0x2a, 0xf6, 0xa1, 0x99, 0x63, 0x0a, 0xd7, 0xfd, 0x70, 0xc3,
+	0xf6, 0x5e, 0x74, 0x13, 0x03, 0xc9, 0x0b, 0x04, 0x26, 0x98, 0xf7, 0x26, 0x8a, 0x92, 0x93, 0x25,
+	0xb0, 0xa2, 0x0d, 0x23, 0xed, 0x63, 0x79, 0x6d, 0x13, 0x32, 0xfa, 0x3c, 0x35, 0x02, 0x9a, 0xa3,
+	0xb3, 0xdd, 0x8e, 0x0a, 0x24, 0xbf, 0x51, 0xc3, 0x7c, 0xcd, 0x55, 0x9f, 0x37, 0xaf, 0x94, 0x4c,
+	0x29, 0x08, 0x52, 0x82, 0xb2, 0x3b, 0x4e, 0x37, 0x9f, 0x17, 0x07, 0x91, 0x11, 0x3b, 0xfd, 0xcd,
+}
+
+// NewPasscode hashes case sensitive password of user (BNAME, upper case,
+// trailing blanks ignored)
+func NewPasscode(user string, pass []byte) Passcode {
+	u := []byte(strings.ToUpper(strings.TrimRight(user, " ")))
+	h := sha1.New()
+	h.Write(pass)
+	h.Write(u)
+	t := h.Sum(nil)
+	var n, off int
+	for i := 0; i < 10; i++ {
+		n += int(t[i] % 6)
+		off += int(t[19-i] % 8)
+	}
+	n += 0x20
+	h.Reset()
+	h.Write(pass)
+	h.Write(passcodeMagic[off : off+n])
+	h.Write(u)
+	return h.Sum(nil)
+}
+
+// Verify password of user against hash
+func (p Passcode) Verify(user string, pass []byte) bool {
+	return !p.IsZero() && subtle.ConstantTimeCompare(p, NewPasscode(user, pass)) == 1
+}
@@ -0,0 +1,35 @@
+package usr02
+
+import "testing"
+
+func TestPasscode(t *testing.T) {
+	testCases := []struct {
+		user, pass, hash string
+	}{
+		{user: "F           ", pass: "X", hash: "646A0AD270DF651065669A45D171EDD62DFE39A1"},
+		{user: "JOHNNY      ", pass: "CYBERPUNK", hash: "7D79B478E70CAAE63C41E0824EAB644B9070D10A"},
+		{user: "VAN", pass: "hauser", hash: "D15597367F24090F0A501962788E9F19B3604E73"},
+		{user: "ROOT", pass: "KID", hash: "1194E38F14B9F3F8DA1B181F14DEB70E7BDCC239"},
+		{user: "MAN", pass: "u", hash: "22886450D0AB90FDA7F91C4F3DD5619175B372EA"},
+		{user: "sap*", pass: "MaStEr", hash: "60A0F7E06D95BC9FB45F605BDF1F7B660E5D5D4E"},
+		{user: "DDIC", pass: "DDIC", hash: "6066CD3147915331EC4C602847D27A75EB3E8F0A"},
+		{user: "USER", pass: "hashcat", hash: "ABCAD719B17E7F794DF7E686E563E9E2D24DE1D0"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.user+tc.pass, func(t *testing.T) {
+			if got := NewPasscode(tc.user, []byte(tc.pass)).String(); got != tc.hash {
+				t.Errorf("got %v, want %v", got, tc.hash)
+			}
+			var p Passcode
+			if err := p.UnmarshalText([]byte(tc.hash)); err != nil {
+				t.Fatal(err)
+			}
+			if !p.Verify(tc.user, []byte(tc.pass)) {
+				t.Error("not verified")
+			}
+			if p.Verify(tc.user, []byte(tc.pass+"x")) {
+				t.Error("wrong password verified")
+			}
+		})
+	}
+}
@@ -0,0 +1,363 @@
+// Package usr02 handles SAP user master records (table USR02)
+//
+// Dumps are delimited text exports with a header line naming the USR02
+// columns, one user per line. Columns are matched by name, so any order
+// and subset is read, unknown columns are ignored:
+//
+//	MANDT	BNAME	USTYP	CODVN	BCODE	PASSCODE	PWDSALTEDHASH	UFLAG	PWDSTATE	LOCNT	TRDAT	PWDCHGDATE
+//
+// Change files use the same format and hold the password columns only,
+// legacy columns stay empty as BCODE is not implemented yet:
+//
+//	MANDT	BNAME	CODVN	BCODE	PASSCODE	PWDSALTEDHASH
+//
+// Password history exports of USH02 (change documents) and USRPWDHISTORY
+// are read the same way into entries with USR02 password columns, tagged
+// with their table. Change time is taken from MODDA and MODTI or from
+// TIMESTAMP:
+//
+//	MANDT	BNAME	MODDA	MODTI	MODBE	USTYP	CODVN	BCODE	PASSCODE	PWDSALTEDHASH
+//	MANDT	BNAME	TIMESTAMP	BCODE	PASSCODE	PWDSALTEDHASH
+package usr02
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dim13/codvn"
+)
+
+// Errors
+var (
+	ErrHeader = errors.New("missing MANDT or BNAME column")
+	ErrFormat = errors.New("unknown format")
+	ErrSize   = errors.New("wrong legacy hash size")
+)
+
+// Record of user master
+type Record struct {
+	Client        string      // MANDT
+	User          string      // BNAME
+	Type          string      // USTYP, user type
+	Codvn         string      // CODVN, password code version
+	Bcode         Bcode       // BCODE, CODVN B hash
+	Passcode      Passcode    // PASSCODE, CODVN F hash
+	PwdSaltedHash codvn.CodvN // PWDSALTEDHASH, CODVN H hash
+	Lock          int         // UFLAG, lock flags
+	PwdState      int         // PWDSTATE
+	Failed        int         // LOCNT, failed logons
+	LastLogon     time.Time   // TRDAT
+	PwdChanged    time.Time   // PWDCHGDATE
+}
+
+// HasHash reports whether PWDSALTEDHASH is set
+func (r Record) HasHash() bool {
+	return r.PwdSaltedHash.Kind != ""
+}
+
+// Code versions
+const (
+	CodvnB = "B" // BCODE only
+	CodvnF = "F" // PASSCODE only
+	CodvnG = "G" // BCODE and PASSCODE
+	CodvnH = "H" // PWDSALTEDHASH only
+	CodvnI = "I" // BCODE, PASSCODE and PWDSALTEDHASH
+)
+
+// User types
+const (
+	Dialog        = "A"
+	System        = "B"
+	Communication = "C"
+	Reference     = "L"
+	Service       = "S"
+)
+
+// Lock flags
+const (
+	LockGlobal = 32  // locked globally by administrator
+	LockAdmin  = 64  // locked locally by administrator
+	LockLogon  = 128 // locked by incorrect logon attempts
+)
+
+// Password states
+const (
+	PwdProductive = 0
+	PwdInitial    = 1
+	PwdExpired    = 2
+)
+
+const dateLayout = "20060102"
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return "00000000"
+	}
+	return t.Format(dateLayout)
+}
+
+func parseDate(s string) (time.Time, error) {
+	if s == "" || strings.Trim(s, "0") == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(dateLayout, s)
+}
+
+func parseInt(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(strings.TrimSpace(s))
+}
+
+type column struct {
+	name   string
+	format func(r *Record) string
+	parse  func(r *Record, s string) error
+}
+
+var columns = []column{
+	{"MANDT",
+		func(r *Record) string { return r.Client },
+		func(r *Record, s string) error { r.Client = s; return nil }},
+	{"BNAME",
+		func(r *Record) string { return r.User },
+		func(r *Record, s string) error { r.User = s; return nil }},
+	{"USTYP",
+		func(r *Record) string { return r.Type },
+		func(r *Record, s string) error { r.Type = s; return nil }},
+	{"CODVN",
+		func(r *Record) string { return r.Codvn },
+		func(r *Record, s string) error { r.Codvn = s; return nil }},
+	{"BCODE",
+		func(r *Record) string { return r.Bcode.String() },
+		func(r *Record, s string) error { return r.Bcode.UnmarshalText([]byte(s)) }},
+	{"PASSCODE",
+		func(r *Record) string { return r.Passcode.String() },
+		func(r *Record, s string) error { return r.Passcode.UnmarshalText([]byte(s)) }},
+	{"PWDSALTEDHASH",
+		func(r *Record) string {
+			if !r.HasHash() {
+				return ""
+			}
+			return r.PwdSaltedHash.String()
+		},
+		func(r *Record, s string) (err error) {
+			if s == "" {
+				r.PwdSaltedHash = codvn.CodvN{}
+				return nil
+			}
+			r.PwdSaltedHash, err = codvn.Parse([]byte(s))
+			return err
+		}},
+	{"UFLAG",
+		func(r *Record) string { return strconv.Itoa(r.Lock) },
+		func(r *Record, s string) (err error) { r.Lock, err = parseInt(s); return err }},
+	{"PWDSTATE",
+		func(r *Record) string { return strconv.Itoa(r.PwdState) },
+		func(r *Record, s string) (err error) { r.PwdState, err = parseInt(s); return err }},
+	{"LOCNT",
+		func(r *Record) string { return strconv.Itoa(r.Failed) },
+		func(r *Record, s string) (err error) { r.Failed, err = parseInt(s); return err }},
+	{"TRDAT",
+		func(r *Record) string { return formatDate(r.LastLogon) },
+		func(r *Record, s string) (err error) { r.LastLogon, err = parseDate(s); return err }},
+	{"PWDCHGDATE",
+		func(r *Record) string { return formatDate(r.PwdChanged) },
+		func(r *Record, s string) (err error) { r.PwdChanged, err = parseDate(s); return err }},
+}
+
+func lookup(name string) (column, bool) {
+	for _, c := range columns {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return column{}, false
+}
+
+// Set column of record from its text value, unknown columns are ignored
+func (r *Record) Set(name, value string) error {
+	c, ok := lookup(strings.ToUpper(name))
+	if !ok {
+		return nil
+	}
+	return c.parse(r, strings.TrimSpace(value))
+}
+
+// Columns of full dump
+func Columns() []string {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.name
+	}
+	return names
+}
+
+// ChangeColumns of change file
+var ChangeColumns = []string{"MANDT", "BNAME", "CODVN", "BCODE", "PASSCODE", "PWDSALTEDHASH"}
+
+// Formats of delimited dumps by name
+var Formats = map[string]rune{
+	"tsv": '\t',
+	"csv": ',',
+}
+
+// Comma returns delimiter of named format
+func Comma(format string) (rune, error) {
+	c, ok := Formats[format]
+	if !ok {
+		return 0, ErrFormat
+	}
+	return c, nil
+}
+
+// ParseError of record
+type ParseError struct {
+	Line   int
+	Column string
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	return "line " + strconv.Itoa(e.Line) + ", column " + e.Column + ": " + e.Err.Error()
+}
+
+// Reader reads dumps
+type Reader struct {
+	r      *csv.Reader
+	header []column
+	line   int
+}
+
+// NewReader returns reader of dump delimited by comma
+func NewReader(r io.Reader, comma rune) *Reader {
+	cr := csv.NewReader(r)
+	cr.Comma = comma
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.ReuseRecord = true
+	return &Reader{r: cr}
+}
+
+func (r *Reader) readHeader() error {
+	fields, err := r.r.Read()
+	if err == io.EOF {
+		return ErrHeader
+	}
+	if err != nil {
+		return err
+	}
+	r.line++
+	var client, user bool
+	r.header = make([]column, len(fields))
+	for i, f := range fields {
+		name := strings.ToUpper(strings.TrimSpace(f))
+		c, ok := lookup(name)
+		if !ok {
+			c = column{name: name}
+		}
+		r.header[i] = c
+		client = client || name == "MANDT"
+		user = user || name == "BNAME"
+	}
+	if !client || !user {
+		return ErrHeader
+	}
+	return nil
+}
+
+// Read next record, returns io.EOF at end of dump
+func (r *Reader) Read() (Record, error) {
+	if r.header == nil {
+		if err := r.readHeader(); err != nil {
+			return Record{}, err
+		}
+	}
+	fields, err := r.r.Read()
+	if err != nil {
+		return Record{}, err
+	}
+	r.line++
+	var rec Record
+	for i, f := range fields {
+		if i >= len(r.header) || r.header[i].parse == nil {
+			continue
+		}
+		if err := r.header[i].parse(&rec, strings.TrimSpace(f)); err != nil {
+			return Record{}, &ParseError{Line: r.line, Column: r.header[i].name, Err: err}
+		}
+	}
+	return rec, nil
+}
+
+// ReadAll remaining records
+func (r *Reader) ReadAll() ([]Record, error) {
+	var recs []Record
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return recs, nil
+		}
+		if err != nil {
+			return recs, err
+		}
+		recs = append(recs, rec)
+	}
+}
+
+// Writer writes dumps
+type Writer struct {
+	w       *csv.Writer
+	columns []column
+	header  bool
+}
+
+// NewWriter returns writer of named columns, all columns if none given
+func NewWriter(w io.Writer, comma rune, names ...string) *Writer {
+	if len(names) == 0 {
+		names = Columns()
+	}
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	wr := &Writer{w: cw}
+	for _, n := range names {
+		if c, ok := lookup(n); ok {
+			wr.columns = append(wr.columns, c)
+		}
+	}
+	return wr
+}
+
+// NewChangeWriter returns change file writer
+func NewChangeWriter(w io.Writer) *Writer {
+	return NewWriter(w, '\t', ChangeColumns...)
+}
+
+// Write record
+func (w *Writer) Write(r Record) error {
+	fields := make([]string, len(w.columns))
+	if !w.header {
+		for i, c := range w.columns {
+			fields[i] = c.name
+		}
+		if err := w.w.Write(fields); err != nil {
+			return err
+		}
+		w.header = true
+	}
+	for i, c := range w.columns {
+		fields[i] = c.format(&r)
+	}
+	return w.w.Write(fields)
+}
+
+// Flush buffered data
+func (w *Writer) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
@@ -0,0 +1,144 @@
+package usr02
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dim13/codvn"
+)
+
+const testHash = `{x-issha,1024}IlU5JC/UaAzvUl8ncaxIBlFQ1Nfd0C5YxkizRFg970g=`
+
+func TestChangeWriter(t *testing.T) {
+	h, err := codvn.Parse([]byte(testHash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w := NewChangeWriter(&buf)
+	if err := w.Write(Record{Client: "001", User: "SAP*", Codvn: CodvnH, PwdSaltedHash: h}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	want := "MANDT\tBNAME\tCODVN\tBCODE\tPASSCODE\tPWDSALTEDHASH\n" +
+		"001\tSAP*\tH\t\t\t" + testHash + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	h, err := codvn.Parse([]byte(testHash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recs := []Record{
+		{
+			Client:        "001",
+			User:          "SAP*",
+			Type:          Dialog,
+			Codvn:         CodvnI,
+			Bcode:         Bcode{1, 2, 3, 4, 5, 6, 7, 8},
+			Passcode:      Passcode(bytes.Repeat([]byte{0xab}, PasscodeSize)),
+			PwdSaltedHash: h,
+			Lock:          LockLogon,
+			PwdState:      PwdInitial,
+			Failed:        5,
+			LastLogon:     time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+			PwdChanged:    time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{Client: "001", User: "RFC_USER", Type: Communication, Codvn: CodvnB, Bcode: Bcode{8, 7, 6, 5, 4, 3, 2, 1}},
+	}
+	for format, comma := range Formats {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriter(&buf, comma)
+			for _, r := range recs {
+				if err := w.Write(r); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			got, err := NewReader(&buf, comma).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, recs) {
+				t.Errorf("got %+v, want %+v", got, recs)
+			}
+		})
+	}
+}
+
+func TestReader(t *testing.T) {
+	testCases := []struct {
+		title string
+		dump  string
+		err   error
+	}{
+		{title: "subset", dump: "bname;mandt;EXTRA\nDDIC;000;x\n"},
+		{title: "empty", dump: "", err: ErrHeader},
+		{title: "header", dump: "BNAME;CODVN\nDDIC;H\n", err: ErrHeader},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			r, err := NewReader(strings.NewReader(tc.dump), ';').Read()
+			if err != tc.err {
+				t.Fatalf("got %v, want %v", err, tc.err)
+			}
+			if tc.err == nil && (r.Client != "000" || r.User != "DDIC") {
+				t.Errorf("got %+v", r)
+			}
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	dump := "MANDT\tBNAME\tBCODE\n000\tDDIC\t0102\n"
+	r := NewReader(strings.NewReader(dump), '\t')
+	_, err := r.Read()
+	pe, ok := err.(*ParseError)
+	if !ok || pe.Line != 2 || pe.Column != "BCODE" || pe.Err != ErrSize {
+		t.Fatalf("got %v", err)
+	}
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("got %v, want %v", err, io.EOF)
+	}
+}
+
+func TestLegacy(t *testing.T) {
+	var b Bcode
+	if err := b.UnmarshalText([]byte("0000000000000000")); err != nil || !b.IsZero() {
+		t.Errorf("got %v %v", b, err)
+	}
+	if err := b.UnmarshalText([]byte("a1b2c3d4e5f60718")); err != nil || b.String() != "A1B2C3D4E5F60718" {
+		t.Errorf("got %v %v", b, err)
+	}
+	var p Passcode
+	if err := p.UnmarshalText([]byte("A1B2C3D4E5F60718")); err != ErrSize {
+		t.Errorf("got %v, want %v", err, ErrSize)
+	}
+}
+
+func TestSet(t *testing.T) {
+	var r Record
+	for _, kv := range [][2]string{{"mandt", "001"}, {"BNAME", " DDIC "}, {"UFLAG", "64"}, {"EXTRA", "x"}} {
+		if err := r.Set(kv[0], kv[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if r.Client != "001" || r.User != "DDIC" || r.Lock != LockAdmin {
+		t.Errorf("got %+v", r)
+	}
+	if err := r.Set("TRDAT", "2024-01-01"); err == nil {
+		t.Error("malformed date accepted")
+	}
+}
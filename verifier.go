@@ -0,0 +1,37 @@
+package codvn
+
+// Verifier rejects hashes weaker than configured before verifying them,
+// so a planted weak hash can't be used to log in
+type Verifier struct {
+	Kinds   []Kind // allowed kinds, any if empty
+	MinIter int    // minimal iterations
+	MinSalt int    // minimal salt size in bytes
+}
+
+// Allowed checks hash against verifier policy
+func (v Verifier) Allowed(c CodvN) error {
+	if len(v.Kinds) > 0 {
+		ok := false
+		for _, k := range v.Kinds {
+			if k == c.Kind {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return ErrPolicy
+		}
+	}
+	if c.Iter < v.MinIter || len(c.Salt) < v.MinSalt {
+		return ErrPolicy
+	}
+	return nil
+}
+
+// Verify hashed password, if hash is allowed
+func (v Verifier) Verify(c CodvN, clear []byte) error {
+	if err := v.Allowed(c); err != nil {
+		return err
+	}
+	return c.Verify(clear)
+}
@@ -0,0 +1,53 @@
+package codvn
+
+import "testing"
+
+func TestVerifier(t *testing.T) {
+	v := Verifier{Kinds: []Kind{SHA256, SHA384, SHA512}, MinIter: 10000, MinSalt: 16}
+	testCases := []struct {
+		title  string
+		hashed string
+		clear  string
+		err    error
+	}{
+		{
+			title:  "allowed",
+			hashed: `{x-isSHA512,15000}lbaY7cwziH2rPfBdr9T3mZKT/DMXstwSzT1mXNipjYxqoIXfmKBIrcfSNkwq/S5DbqtrDCKX7iOnzPhnIyXRitydEZPrB/BseZ799wYL2O0=`,
+			clear:  `testtest`,
+		},
+		{
+			title:  "mismatch",
+			hashed: `{x-isSHA512,15000}lbaY7cwziH2rPfBdr9T3mZKT/DMXstwSzT1mXNipjYxqoIXfmKBIrcfSNkwq/S5DbqtrDCKX7iOnzPhnIyXRitydEZPrB/BseZ799wYL2O0=`,
+			clear:  `wrong`,
+			err:    ErrDontMatch,
+		},
+		{
+			title:  "kind",
+			hashed: `{x-issha,1024}IlU5JC/UaAzvUl8ncaxIBlFQ1Nfd0C5YxkizRFg970g=`,
+			clear:  `Pindakaas!`,
+			err:    ErrPolicy,
+		},
+		{
+			title:  "iterations",
+			hashed: `{x-isSHA256,1}fdsFcKpKZX52VrKN9iv4JzWBXl1TsZuSE9cVcQfPAuJzYWx0c2FsdHNhbHRzYWx0`,
+			err:    ErrPolicy,
+		},
+		{
+			title:  "salt",
+			hashed: `{x-isSHA384,7500}kqOPN/VxvZXpD8zEiRBAe1L6fW6GTXRd/RFl0AbaEbyCZFeMBA8+NKV6MG2Me2u3ZRlCdflPuccjtr55`,
+			clear:  `HashCat!`,
+			err:    ErrPolicy,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			c, err := Parse([]byte(tc.hashed))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := v.Verify(c, []byte(tc.clear)); err != tc.err {
+				t.Errorf("got %v, want %v", err, tc.err)
+			}
+		})
+	}
+}